@@ -9,13 +9,30 @@ import (
 	"github.com/prometheus/exporter-toolkit/web/kingpinflag"
 )
 
+// Commands of the exporter.
+const (
+	// CommandServe runs the exporter, it is the default command.
+	CommandServe = "serve"
+	// CommandDiscover inspects the directory server and prints a configuration for it.
+	CommandDiscover = "discover"
+	// CommandScrape collects the metrics once and writes them to a file.
+	CommandScrape = "scrape"
+	// CommandCheckAttributes reports the collector attributes that the directory server does not return.
+	CommandCheckAttributes = "check-attributes"
+)
+
 // Arguments provides a structure for storing the cli parameters of the exporter.
 type Arguments struct {
+	Command              string
 	ConfigFile           string
 	IsConfigCheck        bool
+	IsConfigSchema       bool
 	PromslogConfig       *promslog.Config
 	ExporterToolkitFlags *web.FlagConfig
 	MetricsPath          string
+	ScrapeOutput         string
+	CheckFormat          string
+	CheckOnStartup       bool
 }
 
 // ParseArguments parses the arguments of the conmad string into the CmdArguments structure.
@@ -24,8 +41,17 @@ func ParseArguments() (*kingpin.Application, *Arguments) {
 	args := &Arguments{}
 
 	configFilePath := new(string)
-	checkConfig := app.Flag("config.check", "Validate the current configuration and print it to stdout").Bool()
+	checkConfig := app.Flag("config.check", "Validate the current configuration and print it to stdout with the source of every value").Bool()
+	printSchema := app.Flag("config.schema", "Print the JSON Schema of the configuration file to stdout").Bool()
 	metricsPath := app.Flag("web.metrics.path", "Path under which to expose metrics.").Default("/metrics").String()
+	once := app.Flag("once",
+		"Collect the metrics once, write them to --scrape.output and exit. Same as the scrape command.").Bool()
+	scrapeOutput := app.Flag("scrape.output",
+		"Path of the file the scrape command writes the metrics to in the Prometheus text format.").String()
+	checkFormat := app.Flag("check-attributes.format", "Output format of the check-attributes command.").
+		Default(CheckFormatText).Enum(CheckFormatText, CheckFormatJSON)
+	checkOnStartup := app.Flag("check-attributes.on-startup",
+		"Check the collector attributes against the directory server at startup and log the problems.").Bool()
 	toolkitFlags := kingpinflag.AddFlags(app, ":9389")
 
 	app.Flag("config.file", "Path to configuration file").
@@ -36,6 +62,32 @@ func ParseArguments() (*kingpin.Application, *Arguments) {
 		Hidden().Default("config.yml").
 		StringVar(configFilePath)
 
+	app.Command(CommandServe, "Run the exporter.").Default().Action(func(*kingpin.ParseContext) error {
+		args.Command = CommandServe
+		if *once {
+			args.Command = CommandScrape
+		}
+		return nil
+	})
+	app.Command(CommandDiscover,
+		"Inspect the directory server, report what the exporter can read and print a config.yml for it.").
+		Action(func(*kingpin.ParseContext) error {
+			args.Command = CommandDiscover
+			return nil
+		})
+	app.Command(CommandScrape,
+		"Collect the metrics once and write them to --scrape.output, e.g. for the node_exporter textfile collector.").
+		Action(func(*kingpin.ParseContext) error {
+			args.Command = CommandScrape
+			return nil
+		})
+	app.Command(CommandCheckAttributes,
+		"Report the collector attributes that are missing, unparseable or whose entry is not returned.").
+		Action(func(*kingpin.ParseContext) error {
+			args.Command = CommandCheckAttributes
+			return nil
+		})
+
 	args.PromslogConfig = &promslog.Config{}
 	flag.AddFlags(app, args.PromslogConfig)
 
@@ -45,8 +97,12 @@ func ParseArguments() (*kingpin.Application, *Arguments) {
 	app.Action(func(*kingpin.ParseContext) error {
 		args.ConfigFile = *configFilePath
 		args.IsConfigCheck = *checkConfig
+		args.IsConfigSchema = *printSchema
 		args.ExporterToolkitFlags = toolkitFlags
 		args.MetricsPath = *metricsPath
+		args.ScrapeOutput = *scrapeOutput
+		args.CheckFormat = *checkFormat
+		args.CheckOnStartup = *checkOnStartup
 		return nil
 	})
 
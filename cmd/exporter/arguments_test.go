@@ -12,6 +12,7 @@ func TestDefaultValues(t *testing.T) {
 	require.NoError(t, err, "Empty cmd args should be parsed successfully")
 	require.Equal(t, "config.yml", args.ConfigFile, "--config.file default value should be config.yml")
 	require.Equal(t, "/metrics", args.MetricsPath, "--web.metrics.path default value should be /metrics")
+	require.Equal(t, CommandServe, args.Command, "serve should be the default command")
 }
 
 func TestDeprecatedConfigFlag(t *testing.T) {
@@ -20,3 +21,43 @@ func TestDeprecatedConfigFlag(t *testing.T) {
 	require.NoError(t, err, "Parsing args with deprecated backward-compatibility '--config' flag should not fail")
 	require.Equal(t, "old.yml", args.ConfigFile, "--config value should be correctly parsed to args")
 }
+
+func TestConfigSchemaFlag(t *testing.T) {
+	app, args := ParseArguments()
+	_, err := app.Parse([]string{"--config.schema"})
+	require.NoError(t, err)
+	require.True(t, args.IsConfigSchema, "--config.schema should be parsed to args")
+}
+
+func TestDiscoverCommand(t *testing.T) {
+	app, args := ParseArguments()
+	_, err := app.Parse([]string{"discover", "--config.file", "ds.yml"})
+	require.NoError(t, err)
+	require.Equal(t, CommandDiscover, args.Command)
+	require.Equal(t, "ds.yml", args.ConfigFile, "Global flags should be accepted after the command")
+}
+
+func TestScrapeCommand(t *testing.T) {
+	app, args := ParseArguments()
+	_, err := app.Parse([]string{"scrape", "--scrape.output", "/tmp/ds.prom"})
+	require.NoError(t, err)
+	require.Equal(t, CommandScrape, args.Command)
+	require.Equal(t, "/tmp/ds.prom", args.ScrapeOutput)
+
+	app, args = ParseArguments()
+	_, err = app.Parse([]string{"--once", "--scrape.output", "/tmp/ds.prom"})
+	require.NoError(t, err)
+	require.Equal(t, CommandScrape, args.Command, "--once should select the scrape command")
+}
+
+func TestCheckAttributesCommand(t *testing.T) {
+	app, args := ParseArguments()
+	_, err := app.Parse([]string{"check-attributes", "--check-attributes.format", "json"})
+	require.NoError(t, err)
+	require.Equal(t, CommandCheckAttributes, args.Command)
+	require.Equal(t, CheckFormatJSON, args.CheckFormat)
+
+	app, _ = ParseArguments()
+	_, err = app.Parse([]string{"check-attributes", "--check-attributes.format", "xml"})
+	require.Error(t, err, "Unknown formats should be rejected")
+}
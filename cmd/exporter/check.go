@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"389-ds-exporter/internal/collectors"
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+	"389-ds-exporter/internal/metrics"
+)
+
+// Output formats of the check-attributes command.
+const (
+	CheckFormatText = "text"
+	CheckFormatJSON = "json"
+)
+
+// runCheckAttributes checks the attributes of all enabled collectors against the directory server
+// and writes the report in the given format. It returns a non-zero exit code if any problem was found.
+func runCheckAttributes(cfg *config.ExporterConfig, format string, out io.Writer) int {
+	pool := expldap.NewLDAPPool(ldapPoolConfig(cfg))
+	defer func() {
+		_ = pool.Close()
+	}()
+
+	_, dsCollector, _ := metrics.SetupPrometheusMetrics(cfg, pool)
+	reports := dsCollector.CheckAttributes(context.Background())
+
+	err := writeAttributeReports(out, reports, format)
+	if err != nil {
+		slog.Error("Error writing the attribute report", "err", err)
+		return 1
+	}
+
+	for _, report := range reports {
+		if !report.OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeAttributeReports writes the attribute reports as JSON or as a human-readable table.
+func writeAttributeReports(out io.Writer, reports []collectors.AttributeReport, format string) error {
+	if format == CheckFormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, report := range reports {
+		switch {
+		case report.Err != "":
+			_, _ = fmt.Fprintf(w, "%s\t%s\terror: %s\n", report.Collector, report.DN, report.Err)
+		case report.EntryMissing:
+			_, _ = fmt.Fprintf(w, "%s\t%s\tno entry returned, check the ACIs of the bind DN\n", report.Collector, report.DN)
+		case report.OK():
+			_, _ = fmt.Fprintf(w, "%s\t%s\tok\n", report.Collector, report.DN)
+		default:
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d attribute problems\n",
+				report.Collector, report.DN, len(report.Missing)+len(report.Unparseable))
+		}
+		for _, attribute := range report.Missing {
+			_, _ = fmt.Fprintf(w, "  - missing\t%s\t\n", attribute)
+		}
+		for _, problem := range report.Unparseable {
+			_, _ = fmt.Fprintf(w, "  - unparseable\t%s\t%q: %s\n", problem.Attribute, problem.Value, problem.Err)
+		}
+	}
+	return w.Flush()
+}
+
+// logAttributeReports logs a warning for every attribute problem found at startup.
+func logAttributeReports(reports []collectors.AttributeReport) {
+	problems := 0
+	for _, report := range reports {
+		switch {
+		case report.Err != "":
+			slog.Warn("Attribute check failed", "collector", report.Collector, "dn", report.DN, "err", report.Err)
+		case report.EntryMissing:
+			slog.Warn("Collector entry was not returned, check the ACIs of the bind DN",
+				"collector", report.Collector, "dn", report.DN)
+		}
+		for _, attribute := range report.Missing {
+			slog.Warn("Collector attribute is missing", "collector", report.Collector, "attribute", attribute)
+		}
+		for _, problem := range report.Unparseable {
+			slog.Warn("Collector attribute cannot be parsed",
+				"collector", report.Collector,
+				"attribute", problem.Attribute,
+				"value", problem.Value,
+				"err", problem.Err)
+		}
+		if !report.OK() {
+			problems++
+		}
+	}
+	slog.Info("Attribute check finished", "collectors", len(reports), "with_problems", problems)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/collectors"
+)
+
+func TestWriteAttributeReports(t *testing.T) {
+	reports := []collectors.AttributeReport{
+		{Collector: "server", DN: "cn=monitor", Missing: []string{"currentconnections"}},
+		{Collector: "snmp-server", DN: "cn=snmp,cn=monitor", EntryMissing: true},
+		{Collector: "ldbm-database", DN: "cn=database,cn=monitor"},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, writeAttributeReports(&text, reports, CheckFormatText))
+	require.Contains(t, text.String(), "1 attribute problems")
+	require.Contains(t, text.String(), "currentconnections")
+	require.Contains(t, text.String(), "no entry returned")
+	require.Regexp(t, `ldbm-database\s+cn=database,cn=monitor\s+ok`, text.String())
+
+	var out bytes.Buffer
+	require.NoError(t, writeAttributeReports(&out, reports, CheckFormatJSON))
+	var decoded []collectors.AttributeReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Equal(t, reports, decoded)
+}
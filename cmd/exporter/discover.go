@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+	"389-ds-exporter/internal/metrics"
+)
+
+// runDiscover inspects the directory server, writes the report to the report writer
+// and the configuration with the discovered backend pinned to the config writer.
+func runDiscover(cfg *config.ExporterConfig, configOut io.Writer, reportOut io.Writer) int {
+	pool := expldap.NewLDAPPool(ldapPoolConfig(cfg))
+	defer func() {
+		_ = pool.Close()
+	}()
+
+	info, err := metrics.Inspect(context.Background(), cfg, pool)
+	if err != nil {
+		slog.Error("Error inspecting the directory server", "err", err)
+		return 1
+	}
+
+	writeDiscoverReport(reportOut, info)
+
+	discovered := *cfg
+	discovered.DSBackendType = info.BackendType
+	discovered.DSBackendDBs = info.Backends
+	_, err = fmt.Fprint(configOut, discoveredConfig(&discovered))
+	if err != nil {
+		slog.Error("Error writing the configuration", "err", err)
+		return 1
+	}
+	return 0
+}
+
+// writeDiscoverReport writes the human-readable report of the directory server inspection.
+func writeDiscoverReport(out io.Writer, info *metrics.ServerInfo) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(w, "Directory server version:\t%s\n", info.Version)
+	_, _ = fmt.Fprintf(w, "Backend implementation:\t%s\n", info.BackendType)
+	_, _ = fmt.Fprintf(w, "Backends:\t%s\n", strings.Join(info.Backends, ", "))
+	_, _ = fmt.Fprintf(w, "Suffixes:\t%s\n", strings.Join(info.Suffixes, ", "))
+
+	_, _ = fmt.Fprintln(w, "\nMonitor entries:")
+	for _, entry := range info.Entries {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n", entry.State, entry.Collector, entry.DN)
+	}
+
+	if len(info.Problems) > 0 {
+		_, _ = fmt.Fprintln(w, "\nMissing permissions and other problems:")
+		for _, problem := range info.Problems {
+			_, _ = fmt.Fprintf(w, "  - %v\n", problem)
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+
+	_ = w.Flush()
+}
+
+// discoveredConfig returns the configuration as a config.yml document.
+// The bind password, the remote-write credentials and the OTLP headers are never printed.
+func discoveredConfig(cfg *config.ExporterConfig) string {
+	safeCfg := *cfg
+	safeCfg.LDAPBindPw = ""
+	safeCfg.RemoteWritePassword = ""
+	safeCfg.RemoteWriteBearerToken = ""
+	safeCfg.OTLPMetricsHeaders = nil
+
+	out := notPrinted(safeCfg.String(), "ldap_bind_pw", "ldap_bind_pw: \"\"\n", "The password is not printed, set it")
+	if len(cfg.OTLPMetricsHeaders) > 0 {
+		out = notPrinted(out, "otlp_metrics_headers", "otlp_metrics_headers: []\n",
+			"The headers are not printed, set them")
+	}
+	if cfg.RemoteWritePassword != "" {
+		out = notPrinted(out, "remote_write_password", "remote_write_password: \"\"\n",
+			"The password is not printed, set it")
+	}
+	if cfg.RemoteWriteBearerToken != "" {
+		out = notPrinted(out, "remote_write_bearer_token", "remote_write_bearer_token: \"\"\n",
+			"The bearer token is not printed, set it")
+	}
+	return out
+}
+
+// notPrinted adds a comment to the line of a cleared secret telling how to set it.
+func notPrinted(out string, key string, line string, comment string) string {
+	return strings.Replace(out, line, "# "+comment+" here or with "+config.EnvName(key)+"\n"+line, 1)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/config"
+)
+
+func TestDiscoveredConfig(t *testing.T) {
+	cfg := &config.ExporterConfig{
+		LDAPServerURL: "ldap://localhost:389",
+		LDAPBindDN:    "cn=directory manager",
+		LDAPBindPw:    "secret",
+		DSBackendType: config.BackendMDB,
+		DSBackendDBs:  []string{"userRoot"},
+	}
+
+	out := discoveredConfig(cfg)
+	require.NotContains(t, out, "secret", "The bind password should not be printed")
+	require.Contains(t, out, "# The password is not printed, set it here or with DS_EXPORTER_LDAP_BIND_PW\n")
+	require.Contains(t, out, "ds_backend_type: mdb\n")
+	require.Contains(t, out, "ds_backend_dbs:\n- userRoot\n")
+	require.Equal(t, "secret", cfg.LDAPBindPw, "The configuration should not be modified")
+	require.NotContains(t, out, "DS_EXPORTER_REMOTE_WRITE_PASSWORD", "Unset secrets should not be commented")
+}
+
+func TestDiscoveredConfigRemoteWrite(t *testing.T) {
+	cfg := &config.ExporterConfig{
+		RemoteWriteURL:         "https://prometheus.example.com/api/v1/write",
+		RemoteWriteUsername:    "exporter",
+		RemoteWritePassword:    "rw-secret",
+		RemoteWriteBearerToken: "token",
+	}
+
+	out := discoveredConfig(cfg)
+	require.NotContains(t, out, "*****", "Secrets should be cleared, not masked")
+	require.NotContains(t, out, "rw-secret")
+	require.Contains(t, out, "# The password is not printed, set it here or with DS_EXPORTER_REMOTE_WRITE_PASSWORD\n"+
+		"remote_write_password: \"\"\n")
+	require.Contains(t, out, "# The bearer token is not printed, set it here or with "+
+		"DS_EXPORTER_REMOTE_WRITE_BEARER_TOKEN\nremote_write_bearer_token: \"\"\n")
+	require.Contains(t, out, "remote_write_username: exporter\n")
+}
+
+func TestDiscoveredConfigOTLPHeaders(t *testing.T) {
+	cfg := &config.ExporterConfig{
+		OTLPMetricsEndpoint: "https://otel.example.com:4318",
+		OTLPMetricsHeaders:  []string{"Authorization=Bearer otlp-secret"},
+	}
+
+	out := discoveredConfig(cfg)
+	require.NotContains(t, out, "*****", "The headers should be cleared, not masked")
+	require.NotContains(t, out, "otlp-secret")
+	require.Contains(t, out, "# The headers are not printed, set them here or with DS_EXPORTER_OTLP_METRICS_HEADERS\n"+
+		"otlp_metrics_headers: []\n")
+}
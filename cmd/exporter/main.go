@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/version"
@@ -19,17 +21,25 @@ import (
 
 	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
 
+	"389-ds-exporter/internal/collectors"
 	"389-ds-exporter/internal/config"
 	exphttp "389-ds-exporter/internal/http"
 	expldap "389-ds-exporter/internal/ldap"
 	"389-ds-exporter/internal/metrics"
+	"389-ds-exporter/internal/otlp"
+	"389-ds-exporter/internal/remotewrite"
+	"389-ds-exporter/internal/tracing"
 )
 
 // appResources struct contains pointers to resources that must be closed when the program terminates.
 // Resources must be added to the structure as they are initialized.
 type appResources struct {
-	ConnPool   *expldap.Pool
-	HttpServer *http.Server
+	ConnPool    *expldap.Pool
+	DSCollector *collectors.DSCollector
+	Discoverer  *metrics.Discoverer
+	OTLPPusher  *otlp.Pusher
+	RemoteWrite *remotewrite.Sender
+	HttpServer  *http.Server
 }
 
 func (r *appResources) shutdown(ctx context.Context) error {
@@ -46,6 +56,33 @@ func (r *appResources) shutdown(ctx context.Context) error {
 		slog.Debug("HTTP server stopped", "err", err)
 	}
 
+	if r.OTLPPusher != nil {
+		slog.Debug("Stopping OTLP metrics push ...")
+		err := r.OTLPPusher.Stop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error stopping OTLP metrics push: %w", err))
+		}
+		slog.Debug("OTLP metrics push stopped", "err", err)
+	}
+
+	if r.RemoteWrite != nil {
+		slog.Debug("Stopping remote write ...")
+		r.RemoteWrite.Stop()
+		slog.Debug("Remote write stopped")
+	}
+
+	if r.Discoverer != nil {
+		slog.Debug("Stopping backend discovery ...")
+		r.Discoverer.Stop()
+		slog.Debug("Backend discovery stopped")
+	}
+
+	if r.DSCollector != nil {
+		slog.Debug("Stopping background collection ...")
+		r.DSCollector.Stop()
+		slog.Debug("Background collection stopped")
+	}
+
 	if r.ConnPool != nil {
 		slog.Debug("Closing LDAP connection pool ...")
 		err := r.ConnPool.Close()
@@ -64,6 +101,12 @@ func (r *appResources) shutdown(ctx context.Context) error {
 
 func readConfig(configFilePath string) (*config.ExporterConfig, error) {
 	configuration, err := config.ReadConfig(configFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Configuration file not found, using environment variables only",
+			"file", configFilePath,
+			"env_prefix", config.EnvPrefix)
+		configuration, err = config.ReadEnvConfig()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error reading configuration: %w", err)
 	}
@@ -76,6 +119,34 @@ func readConfig(configFilePath string) (*config.ExporterConfig, error) {
 	return configuration, nil
 }
 
+// ldapPoolConfig returns the LDAP connection pool configuration of the exporter configuration.
+func ldapPoolConfig(cfg *config.ExporterConfig) expldap.PoolConfig {
+	return expldap.PoolConfig{
+		Auth: expldap.AuthConfig{
+			URL:           cfg.LDAPServerURL,
+			BindDN:        cfg.LDAPBindDN,
+			BindPw:        cfg.LDAPBindPw,
+			DialTimeout:   time.Duration(cfg.LDAPDialTimeout) * time.Second,
+			TlsSkipVerify: cfg.LDAPTlsSkipVerify,
+		},
+		DialTimeout:    time.Duration(cfg.LDAPDialTimeout) * time.Second,
+		MaxConnections: cfg.LDAPPoolConnLimit,
+		MaxIdleTime:    time.Duration(cfg.LDAPPoolIdleTime) * time.Second,
+		MaxLifeTime:    time.Duration(cfg.LDAPPoolLifeTime) * time.Second,
+		ConnFactory:    expldap.RealConnectionDialUrl,
+
+		URLs:             cfg.ServerURLs(),
+		FailoverStrategy: expldap.FailoverStrategy(cfg.LDAPFailoverStrategy),
+		DemotionTime:     time.Duration(cfg.LDAPEndpointDemotionTime) * time.Second,
+
+		MinIdle:           cfg.LDAPPoolMinIdle,
+		IdleCheckInterval: time.Duration(cfg.LDAPPoolIdleCheckInterval) * time.Second,
+
+		BreakerInitialBackoff: time.Duration(cfg.LDAPCircuitInitialBackoff * float64(time.Second)),
+		BreakerMaxBackoff:     time.Duration(cfg.LDAPCircuitMaxBackoff * float64(time.Second)),
+	}
+}
+
 func run() int {
 	var (
 		applicationResources = appResources{}
@@ -90,6 +161,16 @@ func run() int {
 	logger := promslog.New(args.PromslogConfig)
 	slog.SetDefault(logger)
 
+	if args.IsConfigSchema {
+		schema, err := config.Schema()
+		if err != nil {
+			slog.Error("Error generating configuration schema", "err", err)
+			return 1
+		}
+		fmt.Print(string(schema))
+		return 0
+	}
+
 	cfg, err := readConfig(args.ConfigFile)
 	if err != nil {
 		slog.Error("Error loading config", "err", err)
@@ -103,10 +184,36 @@ func run() int {
 	}
 
 	if args.IsConfigCheck {
-		fmt.Print(cfg.String())
+		fmt.Print(cfg.StringWithSources())
 		return 0
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), version.Version)
+	if err != nil {
+		slog.Error("Error setting up tracing", "err", err)
+		return 1
+	}
+	defer func() {
+		shutdownContext, cancel := context.WithTimeout(
+			context.Background(),
+			time.Duration(cfg.ShutdownTimeout)*time.Second,
+		)
+		defer cancel()
+		err := shutdownTracing(shutdownContext)
+		if err != nil {
+			slog.Error("Error flushing traces", "err", err)
+		}
+	}()
+
+	switch args.Command {
+	case CommandDiscover:
+		return runDiscover(cfg, os.Stdout, os.Stderr)
+	case CommandScrape:
+		return runScrape(cfg, args.ScrapeOutput)
+	case CommandCheckAttributes:
+		return runCheckAttributes(cfg, args.CheckFormat, os.Stdout)
+	}
+
 	slog.Info("Configuration read successfully")
 
 	defer func() {
@@ -131,38 +238,73 @@ func run() int {
 		"build_time", version.BuildDate,
 	)
 
-	slog.Info("LDAP server info", "url", cfg.LDAPServerURL, "bind_dn", cfg.LDAPBindDN)
-
-	ldapConnPoolConfig := expldap.PoolConfig{
-		Auth: expldap.AuthConfig{
-			URL:           cfg.LDAPServerURL,
-			BindDN:        cfg.LDAPBindDN,
-			BindPw:        cfg.LDAPBindPw,
-			DialTimeout:   time.Duration(cfg.LDAPDialTimeout) * time.Second,
-			TlsSkipVerify: cfg.LDAPTlsSkipVerify,
-		},
-		DialTimeout:    time.Duration(cfg.LDAPDialTimeout) * time.Second,
-		MaxConnections: cfg.LDAPPoolConnLimit,
-		MaxIdleTime:    time.Duration(cfg.LDAPPoolIdleTime) * time.Second,
-		MaxLifeTime:    time.Duration(cfg.LDAPPoolLifeTime) * time.Second,
-		ConnFactory:    expldap.RealConnectionDialUrl,
-	}
+	slog.Info("LDAP server info", "urls", cfg.ServerURLs(), "bind_dn", cfg.LDAPBindDN)
 
-	applicationResources.ConnPool = expldap.NewLDAPPool(ldapConnPoolConfig)
+	applicationResources.ConnPool = expldap.NewLDAPPool(ldapPoolConfig(cfg))
 
-	dsMetricsRegistry := metrics.SetupPrometheusMetrics(
+	dsMetricsRegistry, dsCollector, discoverer := metrics.SetupPrometheusMetrics(
 		cfg,
 		applicationResources.ConnPool,
 	)
 
+	applicationResources.DSCollector = dsCollector
+	dsCollector.Start()
+	applicationResources.Discoverer = discoverer
+	discoverer.Start()
+
+	if args.CheckOnStartup {
+		go func() {
+			logAttributeReports(dsCollector.CheckAttributes(context.Background()))
+		}()
+	}
+
 	dsMetricsRegistry.MustRegister(versioncollector.NewCollector("ds_exporter"))
 
+	gather := func(ctx context.Context) prometheus.Gatherer {
+		return prometheus.Gatherers{dsMetricsRegistry, dsCollector.Gatherer(ctx)}
+	}
+
+	if cfg.OTLPMetricsEndpoint != "" {
+		pusher, err := otlp.NewPusher(context.Background(), cfg, version.Version, gather)
+		if err != nil {
+			slog.Error("Error setting up OTLP metrics push", "err", err)
+			return 1
+		}
+		applicationResources.OTLPPusher = pusher
+		pusher.Start()
+		slog.Info("OTLP metrics push enabled",
+			"endpoint", cfg.OTLPMetricsEndpoint,
+			"protocol", cfg.OTLPMetricsProtocol,
+			"interval", cfg.OTLPMetricsInterval)
+	}
+
+	if cfg.RemoteWriteURL != "" {
+		applicationResources.RemoteWrite = remotewrite.NewSender(cfg, version.Version, gather)
+		applicationResources.RemoteWrite.Start()
+		slog.Info("Remote write enabled", "url", cfg.RemoteWriteURL, "interval", cfg.RemoteWriteInterval)
+	}
+
 	// Create HTTP server
 	// #nosec G112: HTTP timeouts will be configured later using the exporter-toolkit
 	applicationResources.HttpServer = &http.Server{}
 
 	// Register HTTP endpoinnts
-	http.Handle(args.MetricsPath, promhttp.HandlerFor(dsMetricsRegistry, promhttp.HandlerOpts{}))
+	http.Handle(args.MetricsPath, exphttp.MetricsHttpHandler(
+		dsMetricsRegistry,
+		dsCollector,
+		time.Duration(cfg.ScrapeTimeoutOffset*float64(time.Second)),
+		promhttp.HandlerOpts{},
+	))
+	http.Handle("/metrics.json", exphttp.JSONHttpHandler(
+		dsMetricsRegistry,
+		dsCollector,
+		time.Duration(cfg.ScrapeTimeoutOffset*float64(time.Second)),
+	))
+	http.Handle("/metrics.influx", exphttp.InfluxHttpHandler(
+		dsMetricsRegistry,
+		dsCollector,
+		time.Duration(cfg.ScrapeTimeoutOffset*float64(time.Second)),
+	))
 
 	if args.MetricsPath != "/" {
 		landingConfig := web.LandingConfig{
@@ -176,6 +318,14 @@ func run() int {
 					Address: args.MetricsPath,
 					Text:    "Metrics",
 				},
+				{
+					Address: "/metrics.json",
+					Text:    "Metrics in JSON",
+				},
+				{
+					Address: "/metrics.influx",
+					Text:    "Metrics in InfluxDB line protocol",
+				},
 				{
 					Address: "/health",
 					Text:    "Health",
@@ -190,11 +340,16 @@ func run() int {
 		http.Handle("/", landingPage)
 	}
 
-	http.HandleFunc("/health", exphttp.HealthHttpResponse(
+	health := exphttp.NewHealth(
 		applicationResources.ConnPool,
+		dsCollector,
+		discoverer,
 		startTime,
-		time.Duration(cfg.LDAPPoolGetTimeout)*time.Second),
+		time.Duration(cfg.LDAPPoolGetTimeout)*time.Second,
 	)
+	http.HandleFunc("/health", health.HealthHttpResponse)
+	http.HandleFunc("/health/live", health.LiveHttpResponse)
+	http.HandleFunc("/health/ready", health.ReadyHttpResponse)
 
 	http.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
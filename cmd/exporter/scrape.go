@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
+
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+	"389-ds-exporter/internal/metrics"
+	"389-ds-exporter/internal/tracing"
+)
+
+// runScrape collects the metrics once and writes them to the output file in the Prometheus text format.
+// The file is replaced atomically, so that the textfile collector of node_exporter never reads a partial file.
+// It returns a non-zero exit code if any collector failed.
+func runScrape(cfg *config.ExporterConfig, output string) int {
+	if output == "" {
+		slog.Error("The output file is not specified, use --scrape.output")
+		return 1
+	}
+
+	pool := expldap.NewLDAPPool(ldapPoolConfig(cfg))
+	defer func() {
+		_ = pool.Close()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// A single collection must scrape the server directly, background results and the cache are never ready
+	scrapeCfg := *cfg
+	scrapeCfg.CollectorsBackgroundInterval = 0
+	scrapeCfg.CollectorsMinInterval = 0
+
+	ctx, span := tracing.Tracer().Start(ctx, "scrape")
+	defer span.End()
+
+	registry, dsCollector, _ := metrics.SetupPrometheusMetrics(&scrapeCfg, pool)
+	registry.MustRegister(versioncollector.NewCollector("ds_exporter"))
+
+	err := prometheus.WriteToTextfile(output, prometheus.Gatherers{registry, dsCollector.Gatherer(ctx)})
+	if err != nil {
+		slog.Error("Error writing metrics", "file", output, "err", err)
+		return 1
+	}
+
+	status := dsCollector.Status()
+	failed := 0
+	for _, name := range slices.Sorted(maps.Keys(status)) {
+		if status[name].Err != nil {
+			slog.Error("Collector failed", "collector", name, "err", status[name].Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		slog.Error("Metrics written with failed collectors", "file", output, "failed", failed)
+		return 1
+	}
+
+	slog.Info("Metrics written", "file", output)
+	return 0
+}
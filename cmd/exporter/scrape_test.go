@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/config"
+)
+
+func TestRunScrapeFailedCollectors(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "389ds.prom")
+	cfg := &config.ExporterConfig{
+		LDAPServerURL:                "ldap://127.0.0.1:1",
+		CollectorsDefault:            "standard",
+		CollectorsBackgroundInterval: 60,
+		LDAPPoolConnLimit:            1,
+		LDAPPoolGetTimeout:           1,
+		LDAPDialTimeout:              1,
+	}
+
+	require.Equal(t, 1, runScrape(cfg, output), "Unreachable server should fail the scrape")
+
+	metrics, err := os.ReadFile(output)
+	require.NoError(t, err, "Metrics should be written even if collectors failed")
+	require.Contains(t, string(metrics), "ds_exporter_build_info")
+	require.Contains(t, string(metrics), `ds_exporter_scrape_success{collector="server"} 0`)
+}
+
+func TestRunScrapeNoOutput(t *testing.T) {
+	require.Equal(t, 1, runScrape(&config.ExporterConfig{}, ""))
+}
@@ -0,0 +1,100 @@
+package collectors
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// AttributeProblem describes an attribute that is present in the entry of a collector but cannot be exported.
+type AttributeProblem struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Err       string `json:"error"`
+}
+
+// AttributeReport describes which of the attributes expected by a collector are returned by the server.
+type AttributeReport struct {
+	Collector    string             `json:"collector"`
+	DN           string             `json:"dn"`
+	Err          string             `json:"error,omitempty"` // error of the search, if any
+	EntryMissing bool               `json:"entry_missing"`   // the search returned no entry, usually because of ACIs
+	Missing      []string           `json:"missing_attributes"`
+	Unparseable  []AttributeProblem `json:"unparseable_attributes"`
+}
+
+// OK reports whether all the expected attributes of the collector were returned and parsed.
+func (r *AttributeReport) OK() bool {
+	return r.Err == "" && !r.EntryMissing && len(r.Missing) == 0 && len(r.Unparseable) == 0
+}
+
+// attributeChecker is implemented by the collectors that export a fixed set of LDAP attributes.
+type attributeChecker interface {
+	checkAttributes(ctx context.Context) AttributeReport
+}
+
+// CheckAttributes searches the entries of the registered collectors and reports the expected attributes
+// that are missing or cannot be parsed. Collectors without a fixed set of attributes are skipped.
+// Reports are sorted by collector name.
+func (c *DSCollector) CheckAttributes(ctx context.Context) []AttributeReport {
+	c.mu.RLock()
+	registered := maps.Clone(c.collectors)
+	c.mu.RUnlock()
+
+	reports := make([]AttributeReport, 0, len(registered))
+	for _, name := range slices.Sorted(maps.Keys(registered)) {
+		checker, ok := registered[name].collector.(attributeChecker)
+		if !ok {
+			continue
+		}
+		report := checker.checkAttributes(expldap.WithCollector(ctx, name))
+		report.Collector = name
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// checkAttributes reads the entry of the collector with its own search and checks every monitored attribute.
+func (c *LdapEntryCollector) checkAttributes(ctx context.Context) AttributeReport {
+	report := AttributeReport{
+		DN:          c.baseDn,
+		Missing:     []string{},
+		Unparseable: []AttributeProblem{},
+	}
+
+	attributeList := c.entryAttributes()
+	entry, err := searchBaseObject(ctx, c.connectionPool, c.poolGetTimeout, c.baseDn, attributeList)
+	if err != nil {
+		report.Err = err.Error()
+		return report
+	}
+	if entry == nil {
+		report.EntryMissing = true
+		return report
+	}
+
+	values := filterEntryAttributes(entry, attributeList)
+	for _, key := range slices.Sorted(maps.Keys(c.attributes)) {
+		attribute := c.attributes[key]
+		attributeValues, ok := values[attribute.LdapName]
+		if !ok || len(attributeValues) == 0 {
+			report.Missing = append(report.Missing, attribute.LdapName)
+			continue
+		}
+		if attribute.LdapType == StringLabel {
+			continue
+		}
+
+		_, err := convertAttributeValue(attribute.LdapType, attributeValues[0])
+		if err != nil {
+			report.Unparseable = append(report.Unparseable, AttributeProblem{
+				Attribute: attribute.LdapName,
+				Value:     attributeValues[0],
+				Err:       err.Error(),
+			})
+		}
+	}
+	return report
+}
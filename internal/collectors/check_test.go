@@ -0,0 +1,61 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestCheckAttributes(t *testing.T) {
+	directory := &fakeDirectory{
+		entries: map[string]map[string][]string{
+			"cn=monitor": {
+				"threads":     {"16"},
+				"currenttime": {"yesterday"},
+				"version":     {"389-Directory/2.4.5"},
+			},
+		},
+	}
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("server", NewLdapEntryCollector("server", pool, "cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"threads":      {LdapName: "threads", Type: prometheus.GaugeValue},
+			"current_time": {LdapName: "currenttime", LdapType: Iso8601CompactString, Type: prometheus.GaugeValue},
+			"connections":  {LdapName: "currentconnections", Type: prometheus.GaugeValue},
+			"version":      {LdapName: "version", LdapType: StringLabel, Type: prometheus.GaugeValue},
+		}, nil, time.Second))
+	ds.Register("snmp-server", NewLdapEntryCollector("snmp_server", pool, "cn=snmp,cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"bind_security_errors_total": {LdapName: "bindsecurityerrors", Type: prometheus.CounterValue},
+		}, nil, time.Second))
+	ds.Register("rootdse", NewRootDSECollector(pool, nil, time.Second))
+
+	reports := ds.CheckAttributes(context.Background())
+	require.Len(t, reports, 2, "Collectors without an attribute map should be skipped")
+
+	server := reports[0]
+	require.Equal(t, "server", server.Collector)
+	require.False(t, server.OK())
+	require.False(t, server.EntryMissing)
+	require.Equal(t, []string{"currentconnections"}, server.Missing)
+	require.Len(t, server.Unparseable, 1)
+	require.Equal(t, "currenttime", server.Unparseable[0].Attribute)
+	require.Equal(t, "yesterday", server.Unparseable[0].Value)
+
+	snmp := reports[1]
+	require.Equal(t, "snmp-server", snmp.Collector)
+	require.Equal(t, "cn=snmp,cn=monitor", snmp.DN)
+	require.True(t, snmp.EntryMissing, "Entry hidden from the bind DN should be reported")
+	require.False(t, snmp.OK())
+}
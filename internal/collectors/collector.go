@@ -1,31 +1,125 @@
 package collectors
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"math/big"
+	"path"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	expldap "389-ds-exporter/internal/ldap"
+	"389-ds-exporter/internal/tracing"
 )
 
 const exporterNamespace = "ds"
 
+// ErrUnknownCollector means that the name does not refer to any registered collector.
+var ErrUnknownCollector = errors.New("unknown collector")
+
 // InternalCollector describes the interface of the internal collector that collects data.
+// Get must stop collecting and return as soon as the context is done.
 type InternalCollector interface {
-	Get(chan<- prometheus.Metric) error
+	Get(context.Context, chan<- prometheus.Metric) error
 }
 
-// DSCollector implements prometheus.Collector interface.
-type DSCollector struct {
-	collectors         map[string]InternalCollector
+// DSCollectorConfig provides a structure for storing the DSCollector configuration.
+type DSCollectorConfig struct {
+	// MinInterval is the minimum time between two real scrapes of the same collector.
+	// Scrapes inside the interval are served from the last result. Zero disables caching.
+	MinInterval time.Duration
+	// BackgroundInterval enables the background collection mode when greater than zero.
+	// Every collector is scraped on its own schedule with this interval and Collect
+	// serves the latest snapshot without waiting for LDAP.
+	BackgroundInterval time.Duration
+	// StaleWindow is the time a collector may keep failing in the background mode
+	// before its last successful series are dropped. Zero keeps them forever.
+	StaleWindow time.Duration
+	// Subtree enables fetching the entries of the collectors with subtree searches
+	// when not nil. It is not used in the background mode, where collectors run independently.
+	Subtree *SubtreeSearcher
+}
+
+// scrapeResult stores the result of a single collector scrape.
+type scrapeResult struct {
+	metrics  []prometheus.Metric
+	err      error
+	time     time.Time
+	duration time.Duration
+}
+
+// CollectorStatus describes the last scrape of a collector.
+type CollectorStatus struct {
+	Time     time.Time     // start time of the scrape
+	Duration time.Duration // duration of the scrape
+	Err      error         // error of the scrape, nil if it succeeded
+}
+
+// collectorState stores a registered collector and its last scrape results.
+type collectorState struct {
+	collector InternalCollector
+	cancel    context.CancelFunc // stops the background loop of the collector, nil if it is not running
+
+	sync.Mutex                // protects the following fields
+	last        *scrapeResult // result of the last scrape
+	lastSuccess *scrapeResult // result of the last successful scrape
+	refreshing  chan struct{} // closed when the running cached scrape finishes, nil if none is running
+
+	timeouts atomic.Int64 // number of scrapes that exceeded the deadline
+}
+
+// store saves the scrape result as the last one.
+// The caller must hold the state lock.
+func (s *collectorState) store(result *scrapeResult) {
+	s.last = result
+	if result.err == nil {
+		s.lastSuccess = result
+	}
+}
+
+// dsDescriptors contains descriptors of the DSCollector own metrics.
+type dsDescriptors struct {
 	scrapeDurationDesc *prometheus.Desc
 	scrapeSuccessDesc  *prometheus.Desc
+	scrapeTimeoutsDesc *prometheus.Desc
+	cacheAgeDesc       *prometheus.Desc
+	lastSuccessDesc    *prometheus.Desc
+	staleDesc          *prometheus.Desc
+	subtreeSavedDesc   *prometheus.Desc
+}
+
+// DSCollector implements prometheus.Collector interface.
+type DSCollector struct {
+	dsDescriptors
+
+	cfg       DSCollectorConfig
+	onFailure func(collector string, err error)
+
+	mu         sync.RWMutex // protects collectors, ctx and stop
+	collectors map[string]*collectorState
+	ctx        context.Context // parent context of the background loops while they are running
+	stop       context.CancelFunc
+	wg         sync.WaitGroup
 }
 
 // NewDSCollector creates new DSCollector instance.
-func NewDSCollector() *DSCollector {
+func NewDSCollector(cfg DSCollectorConfig) *DSCollector {
 	collector := DSCollector{
-		collectors: make(map[string]InternalCollector),
+		cfg:        cfg,
+		collectors: make(map[string]*collectorState),
+	}
+	collector.dsDescriptors = dsDescriptors{
 		scrapeDurationDesc: prometheus.NewDesc(
 			prometheus.BuildFQName(exporterNamespace, "exporter_scrape", "duration_seconds"),
 			"Duration of a collector scrape",
@@ -38,6 +132,36 @@ func NewDSCollector() *DSCollector {
 			[]string{"collector"},
 			nil,
 		),
+		scrapeTimeoutsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_scrape", "timeouts_total"),
+			"Number of collector scrapes that exceeded the scrape deadline",
+			[]string{"collector"},
+			nil,
+		),
+		cacheAgeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_collector", "cache_age_seconds"),
+			"Age of the collector result served from the cache",
+			[]string{"collector"},
+			nil,
+		),
+		lastSuccessDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_collector", "last_success_timestamp_seconds"),
+			"Unix time of the last successful background scrape of a collector",
+			[]string{"collector"},
+			nil,
+		),
+		staleDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_collector", "stale"),
+			"Whether the served collector snapshot is older than the last background scrape attempt",
+			[]string{"collector"},
+			nil,
+		),
+		subtreeSavedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter", "subtree_searches_saved"),
+			"Number of LDAP searches saved by the subtree searches during the scrape",
+			nil,
+			nil,
+		),
 	}
 	return &collector
 }
@@ -46,43 +170,515 @@ func NewDSCollector() *DSCollector {
 func (c *DSCollector) Describe(channel chan<- *prometheus.Desc) {
 	channel <- c.scrapeDurationDesc
 	channel <- c.scrapeSuccessDesc
+	channel <- c.scrapeTimeoutsDesc
+	if c.cfg.MinInterval > 0 || c.cfg.BackgroundInterval > 0 {
+		channel <- c.cacheAgeDesc
+	}
+	if c.cfg.BackgroundInterval > 0 {
+		channel <- c.lastSuccessDesc
+		channel <- c.staleDesc
+	} else if c.cfg.Subtree != nil {
+		channel <- c.subtreeSavedDesc
+	}
 }
 
 // Collect initiates the receipt of metrics from all collectors.
+// Collectors are not limited in time, use Gatherer to bind the collection to a context.
 func (c *DSCollector) Collect(channel chan<- prometheus.Metric) {
+	c.collect(context.Background(), channel)
+}
+
+// Gatherer returns a prometheus.Gatherer collecting the DSCollector metrics within ctx.
+// Collectors that do not finish before ctx is done are reported as failed.
+func (c *DSCollector) Gatherer(ctx context.Context) prometheus.Gatherer {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&boundCollector{collector: c, ctx: ctx})
+	return registry
+}
+
+// Filter returns a DSCollector that shares the state of c but runs only the selected collectors.
+// A collector is selected if its name matches one of the collect patterns, or any name if there are none,
+// and does not match any of the exclude patterns. Patterns use the path.Match syntax.
+// Names without wildcards must refer to registered collectors, otherwise ErrUnknownCollector is returned.
+func (c *DSCollector) Filter(collect []string, exclude []string) (*DSCollector, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, pattern := range slices.Concat(collect, exclude) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid collector pattern '%s': %w", pattern, err)
+		}
+		if !strings.ContainsAny(pattern, `*?[\`) && c.collectors[pattern] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, pattern)
+		}
+	}
+
+	filtered := &DSCollector{
+		dsDescriptors: c.dsDescriptors,
+		cfg:           c.cfg,
+		onFailure:     c.onFailure,
+		collectors:    make(map[string]*collectorState),
+	}
+	for name, state := range c.collectors {
+		if len(collect) > 0 && !matchesAny(collect, name) {
+			continue
+		}
+		if matchesAny(exclude, name) {
+			continue
+		}
+		filtered.collectors[name] = state
+	}
+
+	return filtered, nil
+}
+
+// matchesAny reports whether the name matches any of the patterns.
+func matchesAny(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	})
+}
+
+// collect initiates the receipt of metrics from all collectors within ctx.
+func (c *DSCollector) collect(ctx context.Context, channel chan<- prometheus.Metric) {
+	c.mu.RLock()
+	registered := maps.Clone(c.collectors)
+	c.mu.RUnlock()
+
+	if c.cfg.BackgroundInterval > 0 {
+		for name, state := range registered {
+			c.snapshot(name, state, channel)
+		}
+		return
+	}
+
+	var subtree *subtreeScrape
+	if c.cfg.Subtree != nil {
+		internalCollectors := make([]InternalCollector, 0, len(registered))
+		for _, state := range registered {
+			if c.due(state) {
+				internalCollectors = append(internalCollectors, state.collector)
+			}
+		}
+		subtree = c.cfg.Subtree.prepare(internalCollectors)
+		ctx = withSubtreeScrape(ctx, subtree)
+	}
+
 	var wg sync.WaitGroup
-	wg.Add(len(c.collectors))
-	for collector := range c.collectors {
+	wg.Add(len(registered))
+	for name, state := range registered {
 		go func() {
-			c.scrape(collector, channel)
+			c.scrape(ctx, name, state, channel)
 			wg.Done()
 		}()
 	}
 	wg.Wait()
+
+	if subtree != nil {
+		channel <- prometheus.MustNewConstMetric(c.subtreeSavedDesc, prometheus.GaugeValue, float64(subtree.saved()))
+	}
 }
 
-// Register adds a child collector.
+// Register adds a child collector, replacing the collector registered with the same name.
+// Collectors may be registered at any time, in the background mode the collector is
+// scraped in the background as soon as it is registered.
 func (c *DSCollector) Register(name string, collector InternalCollector) {
-	c.collectors[name] = collector
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unregister(name)
+	state := &collectorState{collector: collector}
+	c.collectors[name] = state
+	if c.stop != nil {
+		c.startLoop(name, state)
+	}
+}
+
+// Unregister removes the child collector and stops its background scraping.
+// It reports whether the collector was registered.
+func (c *DSCollector) Unregister(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.unregister(name)
+}
+
+// unregister removes the child collector. The caller must hold the write lock.
+func (c *DSCollector) unregister(name string) bool {
+	state, ok := c.collectors[name]
+	if !ok {
+		return false
+	}
+	if state.cancel != nil {
+		state.cancel()
+	}
+	delete(c.collectors, name)
+	return true
+}
+
+// Names returns the sorted names of the registered collectors.
+func (c *DSCollector) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(c.collectors))
+}
+
+// Entries returns the DNs of the entries read by the registered collectors that read a single LDAP entry,
+// by collector name.
+func (c *DSCollector) Entries() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make(map[string]string)
+	for name, state := range c.collectors {
+		entryCollector, ok := state.collector.(subtreeEntryCollector)
+		if ok {
+			entries[name] = entryCollector.entryDN()
+		}
+	}
+	return entries
+}
+
+// Status returns the result of the last scrape of every registered collector.
+// Collectors that were not scraped yet have a zero Time.
+func (c *DSCollector) Status() map[string]CollectorStatus {
+	c.mu.RLock()
+	states := maps.Clone(c.collectors)
+	c.mu.RUnlock()
+
+	status := make(map[string]CollectorStatus, len(states))
+	for name, state := range states {
+		state.Lock()
+		if state.last != nil {
+			status[name] = CollectorStatus{
+				Time:     state.last.time,
+				Duration: state.last.duration,
+				Err:      state.last.err,
+			}
+		} else {
+			status[name] = CollectorStatus{}
+		}
+		state.Unlock()
+	}
+	return status
+}
+
+// OnFailure sets the function called after every failed scrape of a collector.
+// It must be set before the collector is started or filtered.
+func (c *DSCollector) OnFailure(handler func(collector string, err error)) {
+	c.onFailure = handler
+}
+
+// Start starts the background scraping of the registered collectors.
+// It does nothing unless the background collection mode is enabled.
+func (c *DSCollector) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.BackgroundInterval <= 0 || c.stop != nil {
+		return
+	}
+
+	slog.Info("Starting background collection", "interval", c.cfg.BackgroundInterval)
+	c.ctx, c.stop = context.WithCancel(context.Background())
+	for name, state := range c.collectors {
+		c.startLoop(name, state)
+	}
+}
+
+// startLoop starts the background loop of the collector. The caller must hold the write lock.
+func (c *DSCollector) startLoop(name string, state *collectorState) {
+	var ctx context.Context
+	ctx, state.cancel = context.WithCancel(c.ctx)
+	c.wg.Add(1)
+	go c.backgroundLoop(ctx, name, state)
+}
+
+// Stop stops the background scraping, cancels the running scrapes and waits for them to finish.
+func (c *DSCollector) Stop() {
+	c.mu.Lock()
+	stop := c.stop
+	c.stop = nil
+	c.ctx = nil
+	for _, state := range c.collectors {
+		state.cancel = nil
+	}
+	c.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	stop()
+	c.wg.Wait()
+}
+
+// backgroundLoop scrapes the collector every BackgroundInterval until the collector is stopped.
+// The first scrape is delayed by a random offset so that collectors do not hit LDAP all at once.
+// Every scrape must finish within the interval.
+func (c *DSCollector) backgroundLoop(ctx context.Context, collector string, state *collectorState) {
+	defer c.wg.Done()
+
+	offset := time.Duration(0)
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(c.cfg.BackgroundInterval/10)+1))
+	if err == nil {
+		offset = time.Duration(n.Int64())
+	}
+	timer := time.NewTimer(offset)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		scrapeCtx, cancel := context.WithTimeout(ctx, c.cfg.BackgroundInterval)
+		result := c.run(scrapeCtx, collector, state)
+		cancel()
+
+		state.Lock()
+		state.store(result)
+		state.Unlock()
+
+		timer.Reset(c.cfg.BackgroundInterval)
+	}
+}
+
+// snapshot sends the latest background scrape result of the collector.
+// Series of a collector that has been failing longer than StaleWindow are dropped.
+func (c *DSCollector) snapshot(collector string, state *collectorState, channel chan<- prometheus.Metric) {
+	state.Lock()
+	last, lastSuccess := state.last, state.lastSuccess
+	state.Unlock()
+
+	if last == nil {
+		// The first background scrape has not finished yet
+		return
+	}
+
+	stale := last.err != nil
+	served := last
+	if stale {
+		served = lastSuccess
+		if served != nil && c.cfg.StaleWindow > 0 && time.Since(served.time) > c.cfg.StaleWindow {
+			slog.Debug("Dropping stale collector series", "collector", collector, "last_success", served.time)
+			served = nil
+		}
+	}
+
+	if served != nil {
+		for _, metric := range served.metrics {
+			channel <- metric
+		}
+		channel <- prometheus.MustNewConstMetric(
+			c.cacheAgeDesc,
+			prometheus.GaugeValue,
+			time.Since(served.time).Seconds(),
+			collector)
+	}
+
+	if lastSuccess != nil {
+		channel <- prometheus.MustNewConstMetric(
+			c.lastSuccessDesc,
+			prometheus.GaugeValue,
+			float64(lastSuccess.time.Unix()),
+			collector)
+	}
+
+	c.sendScrapeStatus(collector, state, last, channel)
+
+	if stale {
+		channel <- prometheus.MustNewConstMetric(c.staleDesc, prometheus.GaugeValue, 1, collector)
+	} else {
+		channel <- prometheus.MustNewConstMetric(c.staleDesc, prometheus.GaugeValue, 0, collector)
+	}
 }
 
-// scrape gets collector metrics by name and measures the time of the scrape.
-func (c *DSCollector) scrape(collector string, channel chan<- prometheus.Metric) {
-	start_time := time.Now()
+// due reports whether the collector is going to query LDAP in the current scrape,
+// that is it has no cached result younger than MinInterval and is not being scraped already.
+func (c *DSCollector) due(state *collectorState) bool {
+	if c.cfg.MinInterval <= 0 {
+		return true
+	}
+
+	state.Lock()
+	defer state.Unlock()
+	return state.refreshing == nil && (state.last == nil || time.Since(state.last.time) >= c.cfg.MinInterval)
+}
+
+// scrape gets collector metrics and measures the time of the scrape.
+// If caching is enabled and the last result is younger than MinInterval, it is sent instead.
+func (c *DSCollector) scrape(
+	ctx context.Context,
+	collector string,
+	state *collectorState,
+	channel chan<- prometheus.Metric,
+) {
+	var result *scrapeResult
+
+	if c.cfg.MinInterval > 0 {
+		result = c.cached(ctx, collector, state)
+
+		channel <- prometheus.MustNewConstMetric(
+			c.cacheAgeDesc,
+			prometheus.GaugeValue,
+			time.Since(result.time).Seconds(),
+			collector)
+	} else {
+		result = c.run(ctx, collector, state)
+
+		state.Lock()
+		state.store(result)
+		state.Unlock()
+	}
+
+	for _, metric := range result.metrics {
+		channel <- metric
+	}
+
+	c.sendScrapeStatus(collector, state, result, channel)
+}
+
+// cached returns the last result of the collector if it is younger than MinInterval,
+// otherwise it scrapes the collector.
+// Concurrent scrapes share a single run and wait for it until their own ctx is done.
+// The state lock is not held during the run, so that Status and the snapshots are not blocked by the server.
+// A result that failed only because ctx is done is not cached, the next scrape runs the collector again.
+func (c *DSCollector) cached(ctx context.Context, collector string, state *collectorState) *scrapeResult {
+	start := time.Now()
+	for {
+		state.Lock()
+		last, refreshing := state.last, state.refreshing
+		if last != nil && time.Since(last.time) < c.cfg.MinInterval {
+			state.Unlock()
+			return last
+		}
+		if refreshing == nil {
+			refreshing = make(chan struct{})
+			state.refreshing = refreshing
+			state.Unlock()
+
+			result := c.run(ctx, collector, state)
 
-	err := c.collectors[collector].Get(channel)
+			state.Lock()
+			if ctx.Err() == nil || !errors.Is(result.err, ctx.Err()) {
+				state.store(result)
+			}
+			state.refreshing = nil
+			state.Unlock()
+			close(refreshing)
+			return result
+		}
+		state.Unlock()
 
-	elapsed := time.Since(start_time)
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			result := &scrapeResult{time: start, err: ctx.Err(), duration: time.Since(start)}
+			if errors.Is(result.err, context.DeadlineExceeded) {
+				state.timeouts.Add(1)
+				slog.Error("Collector timed out waiting for a running scrape", "collector", collector,
+					"duration", result.duration)
+			}
+			return result
+		}
+	}
+}
+
+// sendScrapeStatus sends the duration, success and timeouts metrics of the scrape result.
+func (c *DSCollector) sendScrapeStatus(
+	collector string,
+	state *collectorState,
+	result *scrapeResult,
+	channel chan<- prometheus.Metric,
+) {
 	channel <- prometheus.MustNewConstMetric(
 		c.scrapeDurationDesc,
 		prometheus.GaugeValue,
-		float64(elapsed.Seconds()),
+		result.duration.Seconds(),
 		collector)
 
-	if err != nil {
-		slog.Error("Collector failed", "collector", collector, "err", err)
+	if result.err != nil {
 		channel <- prometheus.MustNewConstMetric(c.scrapeSuccessDesc, prometheus.GaugeValue, 0, collector)
 	} else {
 		channel <- prometheus.MustNewConstMetric(c.scrapeSuccessDesc, prometheus.GaugeValue, 1, collector)
 	}
+
+	channel <- prometheus.MustNewConstMetric(
+		c.scrapeTimeoutsDesc,
+		prometheus.CounterValue,
+		float64(state.timeouts.Load()),
+		collector)
+}
+
+// run performs a real scrape of the collector within ctx and buffers its metrics.
+// If ctx is done before the collector returns, the scrape fails with the context error
+// and the metrics sent by the collector afterwards are discarded.
+func (c *DSCollector) run(ctx context.Context, collector string, state *collectorState) *scrapeResult {
+	ctx, span := tracing.Tracer().Start(ctx, "collector "+collector,
+		trace.WithAttributes(attribute.String("ds.collector", collector)))
+
+	metricsCh := make(chan prometheus.Metric)
+	result := &scrapeResult{time: time.Now()}
+
+	var buffered []prometheus.Metric
+	collected := make(chan struct{})
+	go func() {
+		for metric := range metricsCh {
+			buffered = append(buffered, metric)
+		}
+		close(collected)
+	}()
+
+	finished := make(chan error, 1)
+	go func() {
+		finished <- state.collector.Get(expldap.WithCollector(ctx, collector), metricsCh)
+		close(metricsCh)
+	}()
+
+	select {
+	case err := <-finished:
+		<-collected
+		result.metrics = buffered
+		result.err = err
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	result.duration = time.Since(result.time)
+
+	if errors.Is(result.err, context.DeadlineExceeded) {
+		state.timeouts.Add(1)
+		slog.Error("Collector timed out", "collector", collector, "duration", result.duration)
+	} else if result.err != nil && !errors.Is(result.err, context.Canceled) {
+		slog.Error("Collector failed", "collector", collector, "err", result.err)
+	}
+
+	if result.err != nil && !errors.Is(result.err, context.Canceled) && c.onFailure != nil {
+		c.onFailure(collector, result.err)
+	}
+
+	span.SetAttributes(attribute.Int("ds.metrics", len(result.metrics)))
+	tracing.EndSpan(span, result.err)
+	return result
+}
+
+// boundCollector implements prometheus.Collector collecting the DSCollector metrics within ctx.
+type boundCollector struct {
+	collector *DSCollector
+	ctx       context.Context
+}
+
+// Describe boundCollector metrics.
+func (b *boundCollector) Describe(channel chan<- *prometheus.Desc) {
+	b.collector.Describe(channel)
+}
+
+// Collect initiates the receipt of metrics from all collectors within the bound context.
+func (b *boundCollector) Collect(channel chan<- prometheus.Metric) {
+	b.collector.collect(b.ctx, channel)
 }
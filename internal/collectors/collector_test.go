@@ -0,0 +1,377 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeCollector counts calls and sends a single gauge with the call number.
+type fakeCollector struct {
+	calls atomic.Int64
+	err   error
+	desc  *prometheus.Desc
+}
+
+func newFakeCollector(err error) *fakeCollector {
+	return &fakeCollector{
+		err:  err,
+		desc: prometheus.NewDesc("ds_fake_calls", "Number of calls", nil, nil),
+	}
+}
+
+func (f *fakeCollector) Get(_ context.Context, channel chan<- prometheus.Metric) error {
+	calls := f.calls.Add(1)
+	channel <- prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue, float64(calls))
+	return f.err
+}
+
+// gather collects the DSCollector through a registry and returns the number of samples per metric name.
+func gather(t *testing.T, ds *DSCollector) map[string]int {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(ds)
+
+	families, err := registry.Gather()
+	require.NoError(t, err, "Gathering metrics should not fail")
+
+	result := make(map[string]int)
+	for _, family := range families {
+		result[family.GetName()] = len(family.GetMetric())
+	}
+	return result
+}
+
+func TestCollectWithoutCache(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("fake", fake)
+
+	for range 3 {
+		_ = gather(t, ds)
+	}
+	require.Equal(t, int64(3), fake.calls.Load(), "Every collect should scrape the collector when caching is disabled")
+}
+
+func TestCollectServedFromCache(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Hour})
+	ds.Register("fake", fake)
+
+	for range 3 {
+		samples := gather(t, ds)
+		require.Equal(t, 1, samples["ds_fake_calls"], "Cached metrics should be sent on every collect")
+		require.Equal(t, 1, samples["ds_exporter_collector_cache_age_seconds"])
+	}
+	require.Equal(t, int64(1), fake.calls.Load(), "Collects inside the interval should not scrape the collector")
+}
+
+func TestCacheExpiration(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: 10 * time.Millisecond})
+	ds.Register("fake", fake)
+
+	_ = gather(t, ds)
+	time.Sleep(20 * time.Millisecond)
+	_ = gather(t, ds)
+	require.Equal(t, int64(2), fake.calls.Load(), "Collect after the interval should scrape the collector again")
+}
+
+func TestFailedScrapeIsCached(t *testing.T) {
+	fake := newFakeCollector(errors.New("ldap is down"))
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Hour})
+	ds.Register("fake", fake)
+
+	_ = gather(t, ds)
+	_ = gather(t, ds)
+	require.Equal(t, int64(1), fake.calls.Load(), "Failed scrapes should be cached too to cap the load")
+}
+
+// switchCollector fails while failing is set.
+type switchCollector struct {
+	*fakeCollector
+	failing atomic.Bool
+}
+
+func (s *switchCollector) Get(ctx context.Context, channel chan<- prometheus.Metric) error {
+	if s.failing.Load() {
+		s.calls.Add(1)
+		return errors.New("ldap is down")
+	}
+	return s.fakeCollector.Get(ctx, channel)
+}
+
+func TestBackgroundCollection(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{BackgroundInterval: 10 * time.Millisecond})
+	ds.Register("fake", fake)
+
+	require.Empty(t, gather(t, ds)["ds_fake_calls"], "No series should be served before the first background scrape")
+
+	ds.Start()
+	defer ds.Stop()
+
+	require.Eventually(t, func() bool { return fake.calls.Load() >= 3 }, time.Second, time.Millisecond,
+		"Collector should be scraped in the background")
+
+	calls := fake.calls.Load()
+	samples := gather(t, ds)
+	require.Equal(t, 1, samples["ds_fake_calls"])
+	require.Equal(t, 1, samples["ds_exporter_collector_stale"])
+	require.Equal(t, 1, samples["ds_exporter_collector_last_success_timestamp_seconds"])
+	require.LessOrEqual(t, fake.calls.Load()-calls, int64(1), "Collect should not scrape the collector itself")
+}
+
+func TestBackgroundStaleSeriesDropped(t *testing.T) {
+	collector := &switchCollector{fakeCollector: newFakeCollector(nil)}
+	ds := NewDSCollector(DSCollectorConfig{
+		BackgroundInterval: 5 * time.Millisecond,
+		StaleWindow:        50 * time.Millisecond,
+	})
+	ds.Register("switch", collector)
+	ds.Start()
+	defer ds.Stop()
+
+	require.Eventually(t, func() bool { return gather(t, ds)["ds_fake_calls"] == 1 }, time.Second, time.Millisecond)
+
+	collector.failing.Store(true)
+	calls := collector.calls.Load()
+	require.Eventually(t, func() bool { return collector.calls.Load() > calls+1 }, time.Second, time.Millisecond)
+	require.Equal(t, 1, gather(t, ds)["ds_fake_calls"], "Last successful series should be served while inside the window")
+
+	require.Eventually(t, func() bool { return gather(t, ds)["ds_fake_calls"] == 0 }, time.Second, 5*time.Millisecond,
+		"Series of a collector failing longer than the window should be dropped")
+	require.Equal(t, 1, gather(t, ds)["ds_exporter_collector_last_success_timestamp_seconds"])
+}
+
+// blockingCollector counts calls, ignores the context and blocks until it is released.
+type blockingCollector struct {
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (b *blockingCollector) Get(_ context.Context, _ chan<- prometheus.Metric) error {
+	b.calls.Add(1)
+	<-b.release
+	return nil
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	blocking := &blockingCollector{release: make(chan struct{})}
+	defer close(blocking.release)
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("blocking", blocking)
+	ds.Register("fake", newFakeCollector(nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	families, err := ds.Gatherer(ctx).Gather()
+	require.NoError(t, err, "Gathering metrics should not fail")
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "/" + label.GetValue()
+			}
+			values[key] = metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+		}
+	}
+
+	require.InDelta(t, 0.0, values["ds_exporter_scrape_success/blocking"], 0, "Timed out collector should fail")
+	require.InDelta(t, 1.0, values["ds_exporter_scrape_timeouts_total/blocking"], 0)
+	require.InDelta(t, 1.0, values["ds_exporter_scrape_success/fake"], 0, "Other collectors should not be affected")
+	require.InDelta(t, 0.0, values["ds_exporter_scrape_timeouts_total/fake"], 0)
+}
+
+func TestCachedScrapeDoesNotBlockStatus(t *testing.T) {
+	blocking := &blockingCollector{release: make(chan struct{})}
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Minute})
+	ds.Register("blocking", blocking)
+
+	gathered := make(chan struct{})
+	go func() {
+		_, _ = ds.Gatherer(context.Background()).Gather()
+		close(gathered)
+	}()
+
+	status := make(chan map[string]CollectorStatus)
+	go func() {
+		// Let the scrape start before asking for the status
+		time.Sleep(20 * time.Millisecond)
+		status <- ds.Status()
+	}()
+
+	select {
+	case got := <-status:
+		require.Equal(t, CollectorStatus{}, got["blocking"], "The running scrape should not have a status yet")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Status should not wait for a running cached scrape")
+	}
+
+	close(blocking.release)
+	<-gathered
+	require.NoError(t, ds.Status()["blocking"].Err)
+}
+
+func TestCachedScrapeWaitRespectsContext(t *testing.T) {
+	blocking := &blockingCollector{release: make(chan struct{})}
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Minute})
+	ds.Register("blocking", blocking)
+
+	gathered := make(chan struct{})
+	go func() {
+		_, _ = ds.Gatherer(context.Background()).Gather()
+		close(gathered)
+	}()
+	require.Eventually(t, func() bool { return blocking.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	families, err := ds.Gatherer(ctx).Gather()
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 2*time.Second, "A waiting scrape should give up when its own deadline expires")
+
+	for _, family := range families {
+		if family.GetName() == "ds_exporter_scrape_success" {
+			require.InDelta(t, 0.0, family.GetMetric()[0].GetGauge().GetValue(), 0)
+		}
+	}
+	require.Equal(t, int64(1), blocking.calls.Load(), "The waiting scrape should not run the collector")
+
+	close(blocking.release)
+	<-gathered
+}
+
+func TestTimedOutScrapeIsNotCached(t *testing.T) {
+	blocking := &blockingCollector{release: make(chan struct{})}
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Minute})
+	ds.Register("blocking", blocking)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := ds.Gatherer(ctx).Gather()
+	require.NoError(t, err)
+	close(blocking.release)
+
+	_, err = ds.Gatherer(context.Background()).Gather()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), blocking.calls.Load(), "A scrape that ran out of time should not be served from the cache")
+	require.NoError(t, ds.Status()["blocking"].Err)
+}
+
+func TestFilter(t *testing.T) {
+	ds := NewDSCollector(DSCollectorConfig{})
+	for _, name := range []string{"server", "snmp-server", "ldbm-instance_userRoot", "ldbm-instance_changelog"} {
+		ds.Register(name, newFakeCollector(nil))
+	}
+
+	filtered, err := ds.Filter([]string{"server", "ldbm-instance_*"}, []string{"ldbm-instance_changelog"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"server", "ldbm-instance_userRoot"}, slices.Collect(maps.Keys(filtered.collectors)))
+
+	filtered, err = ds.Filter(nil, []string{"ldbm-instance_*"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"server", "snmp-server"}, slices.Collect(maps.Keys(filtered.collectors)))
+
+	_, err = ds.Filter([]string{"no-such-collector"}, nil)
+	require.ErrorIs(t, err, ErrUnknownCollector, "Unknown collector name should be rejected")
+
+	_, err = ds.Filter(nil, []string{"ldbm-instance_["})
+	require.Error(t, err, "Malformed pattern should be rejected")
+}
+
+func TestFilterSharesCache(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{MinInterval: time.Hour})
+	ds.Register("fake", fake)
+
+	_ = gather(t, ds)
+	filtered, err := ds.Filter([]string{"fake"}, nil)
+	require.NoError(t, err)
+	_ = gather(t, filtered)
+	require.Equal(t, int64(1), fake.calls.Load(), "Filtered collector should use the shared cache")
+}
+
+func TestRegisterWhileRunning(t *testing.T) {
+	fake := newFakeCollector(nil)
+	ds := NewDSCollector(DSCollectorConfig{BackgroundInterval: 5 * time.Millisecond})
+	ds.Start()
+	defer ds.Stop()
+
+	ds.Register("fake", fake)
+	require.Eventually(t, func() bool { return gather(t, ds)["ds_fake_calls"] == 1 }, time.Second, time.Millisecond,
+		"Collector registered after Start should be scraped in the background")
+
+	require.True(t, ds.Unregister("fake"))
+	require.False(t, ds.Unregister("fake"), "Collector should be unregistered only once")
+	require.Empty(t, ds.Names())
+	require.Empty(t, gather(t, ds)["ds_fake_calls"], "Unregistered collector should not be served")
+
+	calls := fake.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	require.LessOrEqual(t, fake.calls.Load()-calls, int64(1), "Background loop of unregistered collector should stop")
+}
+
+func TestOnFailure(t *testing.T) {
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("failing", newFakeCollector(errors.New("no such object")))
+	ds.Register("fake", newFakeCollector(nil))
+
+	var failed []string
+	ds.OnFailure(func(collector string, _ error) { failed = append(failed, collector) })
+
+	_, _ = ds.Gatherer(context.Background()).Gather()
+	require.Equal(t, []string{"failing"}, failed, "Only failed collectors should be reported")
+}
+
+func TestCollectorSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ds := setupSubtreeCollector(t, newFakeDirectory(), DSCollectorConfig{})
+	ds.Register("failing", newFakeCollector(errors.New("scrape failed")))
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "scrape")
+	_, err := ds.Gatherer(ctx).Gather()
+	require.NoError(t, err)
+	root.End()
+
+	collectorSpans := make(map[string]sdktrace.ReadOnlySpan)
+	var searches int
+	for _, span := range recorder.Ended() {
+		switch {
+		case strings.HasPrefix(span.Name(), "collector "):
+			collectorSpans[span.Name()] = span
+			require.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(),
+				"Collector spans should be children of the scrape span")
+		case span.Name() == "ldap.search":
+			searches++
+			require.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID(),
+				"LDAP spans should belong to the scrape trace")
+		}
+	}
+	require.Len(t, collectorSpans, 4)
+	require.Equal(t, 2, searches, "The subtree search and the search of numsubordinates should be traced")
+	require.Equal(t, codes.Error, collectorSpans["collector failing"].Status().Code)
+	require.Equal(t, codes.Unset, collectorSpans["collector server"].Status().Code)
+}
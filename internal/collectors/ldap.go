@@ -46,14 +46,25 @@ type LdapMonitoredAttribute struct {
 	Labels   prometheus.Labels
 }
 
+// parseErrorKey identifies the attribute parse errors counter.
+type parseErrorKey struct {
+	attribute string
+	valueType string
+}
+
 // LdapEntryCollector collects 389-ds metrics.
 type LdapEntryCollector struct {
 	connectionPool *expldap.Pool
 	baseDn         string
 	attributes     map[string]LdapMonitoredAttribute
 	descriptors    map[string]*prometheus.Desc
-	mutex          sync.Mutex
+	mutex          sync.Mutex // serializes Get and protects parseErrors
 	poolGetTimeout time.Duration
+
+	attributeMissingDesc *prometheus.Desc
+	parseErrorsDesc      *prometheus.Desc
+	entryMissingDesc     *prometheus.Desc
+	parseErrors          map[parseErrorKey]uint64
 }
 
 // NewLdapEntryCollector function create new LdapEntryCollector instance based on provided parameters.
@@ -91,18 +102,47 @@ func NewLdapEntryCollector(
 		attributes:     attributes,
 		descriptors:    metricsDescriptors,
 		poolGetTimeout: poolGetTimeout,
+		attributeMissingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter", "attribute_missing"),
+			"Whether an attribute expected by a collector is missing from the LDAP entry",
+			[]string{"collector", "attribute"},
+			nil,
+		),
+		parseErrorsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter", "attribute_parse_errors_total"),
+			"Number of attribute values that could not be converted to a metric value",
+			[]string{"collector", "attribute", "type"},
+			nil,
+		),
+		entryMissingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter", "entry_missing"),
+			"Whether the LDAP search of a collector returned no entry",
+			[]string{"collector", "dn"},
+			nil,
+		),
+		parseErrors: make(map[parseErrorKey]uint64),
 	}
 }
 
 // Get function fetches metrics from LDAP and sends them to the provided channel.
-func (c *LdapEntryCollector) Get(channel chan<- prometheus.Metric) error {
+func (c *LdapEntryCollector) Get(ctx context.Context, channel chan<- prometheus.Metric) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	ldapEntries, err := c.getLdapEntryAttributes()
+	collector := expldap.CollectorFromContext(ctx)
+
+	ldapEntries, err := c.getLdapEntryAttributes(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting attrs from LDAP: %w", err)
 	}
+	if ldapEntries == nil {
+		channel <- prometheus.MustNewConstMetric(c.entryMissingDesc, prometheus.GaugeValue, 1, collector, c.baseDn)
+		c.sendParseErrors(collector, channel)
+		return nil
+	}
+	channel <- prometheus.MustNewConstMetric(c.entryMissingDesc, prometheus.GaugeValue, 0, collector, c.baseDn)
+	c.sendAttributesMissing(collector, ldapEntries, channel)
+
 	var result error = nil
 
 	for key, value := range c.attributes {
@@ -112,7 +152,6 @@ func (c *LdapEntryCollector) Get(channel chan<- prometheus.Metric) error {
 
 		if !ok {
 			slog.Debug("Attribute was not found in LDAP response. ", "attr_name", value.LdapName)
-
 			continue
 		}
 
@@ -122,40 +161,22 @@ func (c *LdapEntryCollector) Get(channel chan<- prometheus.Metric) error {
 
 		var converted float64
 
-		if value.LdapType == Iso8601CompactString {
-			parsedTime, err := time.Parse(dateTimeLayout, attributeValues[0])
-			if err != nil {
-				slog.Debug(
-					"Error converting date to type float64",
-					"attr_name",
-					key,
-					"attr_value",
-					attributeValues[0],
-					"err",
-					err,
-				)
-
-				result = fmt.Errorf(
-					"error converting attribute value to float64: %w",
-					err,
-				)
-				continue
-			}
-
-			converted = float64(parsedTime.Unix())
-		} else if value.LdapType == StringLabel {
+		if value.LdapType == StringLabel {
 			converted = 1
 			labelValues = append(labelValues, attributeValues[0])
 		} else {
-			converted, err = strconv.ParseFloat(ldapEntries[value.LdapName][0], 64)
+			converted, err = convertAttributeValue(value.LdapType, attributeValues[0])
 			if err != nil {
 				slog.Debug(
 					"Error converting attribute value to type float64",
 					"attr_name",
 					key,
 					"attr_value",
-					ldapEntries[value.LdapName],
+					attributeValues[0],
+					"err",
+					err,
 				)
+				c.parseErrors[parseErrorKey{attribute: value.LdapName, valueType: parseType(value.LdapType)}]++
 				result = fmt.Errorf(
 					"error converting attribute value to float64: %w",
 					err,
@@ -167,19 +188,130 @@ func (c *LdapEntryCollector) Get(channel chan<- prometheus.Metric) error {
 		channel <- prometheus.MustNewConstMetric(c.descriptors[key],
 			c.attributes[key].Type, converted, labelValues...)
 	}
+	c.sendParseErrors(collector, channel)
 	return result
 }
 
-// getLdapEntryAttributes returs the record attributes specified in the LdapEntryCollector from the ldap.
-func (c *LdapEntryCollector) getLdapEntryAttributes() (map[string][]string, error) {
+// sendAttributesMissing sends 1 for every expected attribute missing from the entry and 0 for the others.
+func (c *LdapEntryCollector) sendAttributesMissing(
+	collector string,
+	ldapEntries map[string][]string,
+	channel chan<- prometheus.Metric,
+) {
+	sent := make(map[string]bool, len(c.attributes))
+	for _, value := range c.attributes {
+		if sent[value.LdapName] {
+			continue
+		}
+		sent[value.LdapName] = true
+
+		missing := 0.0
+		if _, ok := ldapEntries[value.LdapName]; !ok {
+			missing = 1
+		}
+		channel <- prometheus.MustNewConstMetric(
+			c.attributeMissingDesc,
+			prometheus.GaugeValue,
+			missing,
+			collector,
+			value.LdapName)
+	}
+}
+
+// sendParseErrors sends the counters of the attribute values that failed to convert.
+// The caller must hold the collector mutex.
+func (c *LdapEntryCollector) sendParseErrors(collector string, channel chan<- prometheus.Metric) {
+	for key, count := range c.parseErrors {
+		channel <- prometheus.MustNewConstMetric(
+			c.parseErrorsDesc,
+			prometheus.CounterValue,
+			float64(count),
+			collector,
+			key.attribute,
+			key.valueType)
+	}
+}
+
+// parseType returns the name of the conversion used for the attribute value type.
+func parseType(valueType LdapAttrValueType) string {
+	if valueType == Iso8601CompactString {
+		return "time"
+	}
+	return "float"
+}
+
+// convertAttributeValue converts the LDAP attribute value of a numeric or date type to float64.
+func convertAttributeValue(valueType LdapAttrValueType, value string) (float64, error) {
+	if valueType == Iso8601CompactString {
+		parsedTime, err := time.Parse(dateTimeLayout, value)
+		if err != nil {
+			return 0, err
+		}
+		return float64(parsedTime.Unix()), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// entryDN returns the DN of the entry read by the collector.
+func (c *LdapEntryCollector) entryDN() string {
+	return c.baseDn
+}
+
+// entryAttributes returns the LDAP attributes read by the collector.
+func (c *LdapEntryCollector) entryAttributes() []string {
 	attributeList := make([]string, 0, len(c.attributes))
 
 	for _, monitoredAttr := range c.attributes {
 		attributeList = append(attributeList, monitoredAttr.LdapName)
 	}
+	return attributeList
+}
+
+// getLdapEntryAttributes returs the record attributes specified in the LdapEntryCollector from the ldap.
+// If the entry was already fetched by a subtree search of the current scrape, it is used instead.
+// It returns nil attributes if the entry was not found.
+func (c *LdapEntryCollector) getLdapEntryAttributes(ctx context.Context) (map[string][]string, error) {
+	attributeList := c.entryAttributes()
+
+	entry, ok := subtreeEntry(ctx, c.baseDn)
+	if ok {
+		if entry == nil {
+			slog.Warn(
+				"LDAP subtree search returned no entry. "+
+					"The configuration may be incorrect or the user may not have permissions",
+				"req_dn", c.baseDn,
+				"req_attrs", attributeList)
+			return nil, nil
+		}
+		return filterEntryAttributes(entry, attributeList), nil
+	}
 
+	entry, err := searchBaseObject(ctx, c.connectionPool, c.poolGetTimeout, c.baseDn, attributeList)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		slog.Warn("LDAP request returned no entries. The configuration may be incorrect or the user may not have permissions",
+			"req_dn", c.baseDn,
+			"req_attrs", attributeList)
+		return nil, nil
+	}
+
+	return filterEntryAttributes(entry, attributeList), nil
+}
+
+// searchBaseObject reads the attributes of a single entry with a base-object search.
+// It returns a nil entry if the search found nothing.
+func searchBaseObject(
+	ctx context.Context,
+	connectionPool *expldap.Pool,
+	poolGetTimeout time.Duration,
+	dn string,
+	attributeList []string,
+) (*ldap.Entry, error) {
 	searchAttributesRequest := ldap.NewSearchRequest(
-		c.baseDn,
+		dn,
 		ldap.ScopeBaseObject,
 		ldap.NeverDerefAliases,
 		1,
@@ -190,16 +322,16 @@ func (c *LdapEntryCollector) getLdapEntryAttributes() (map[string][]string, erro
 		nil,
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.poolGetTimeout)
+	connCtx, cancel := context.WithTimeout(ctx, poolGetTimeout)
 	defer cancel()
-	conn, err := c.connectionPool.Conn(ctx)
+	conn, err := connectionPool.Conn(connCtx)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
 	}
 	defer conn.Close()
 
-	searchResult, err := conn.Search(searchAttributesRequest)
+	searchResult, err := conn.Search(ctx, searchAttributesRequest)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"LDAP Search request (dn='%v', attrs='%v') failed with error: %w",
@@ -209,16 +341,18 @@ func (c *LdapEntryCollector) getLdapEntryAttributes() (map[string][]string, erro
 		)
 	}
 
-	returnValue := make(map[string][]string)
-
 	if len(searchResult.Entries) < 1 {
-		slog.Warn("LDAP request returned no entries. The configuration may be incorrect or the user may not have permissions",
-			"req_dn", searchAttributesRequest.BaseDN,
-			"req_attrs", searchAttributesRequest.Attributes)
-		return returnValue, nil
+		return nil, nil
 	}
 
-	for _, attr := range searchResult.Entries[0].Attributes {
+	return searchResult.Entries[0], nil
+}
+
+// filterEntryAttributes returns the values of the listed attributes of the entry.
+func filterEntryAttributes(entry *ldap.Entry, attributeList []string) map[string][]string {
+	returnValue := make(map[string][]string)
+
+	for _, attr := range entry.Attributes {
 		if !slices.Contains(attributeList, attr.Name) {
 			continue
 		}
@@ -226,5 +360,5 @@ func (c *LdapEntryCollector) getLdapEntryAttributes() (map[string][]string, erro
 		returnValue[attr.Name] = attr.Values
 	}
 
-	return returnValue, nil
+	return returnValue
 }
@@ -0,0 +1,64 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestLdapEntryCollectorDataQuality(t *testing.T) {
+	directory := &fakeDirectory{
+		entries: map[string]map[string][]string{
+			"cn=monitor": {
+				"threads":     {"many"},
+				"currenttime": {"yesterday"},
+			},
+		},
+	}
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("server", NewLdapEntryCollector("server", pool, "cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"threads":      {LdapName: "threads", Type: prometheus.GaugeValue},
+			"current_time": {LdapName: "currenttime", LdapType: Iso8601CompactString, Type: prometheus.GaugeValue},
+			"connections":  {LdapName: "currentconnections", Type: prometheus.GaugeValue},
+		}, nil, time.Second))
+	ds.Register("snmp-server", NewLdapEntryCollector("snmp_server", pool, "cn=snmp,cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"bind_security_errors_total": {LdapName: "bindsecurityerrors", Type: prometheus.CounterValue},
+		}, nil, time.Second))
+
+	expected := `
+# HELP ds_exporter_attribute_missing Whether an attribute expected by a collector is missing from the LDAP entry
+# TYPE ds_exporter_attribute_missing gauge
+ds_exporter_attribute_missing{attribute="currentconnections",collector="server"} 1
+ds_exporter_attribute_missing{attribute="currenttime",collector="server"} 0
+ds_exporter_attribute_missing{attribute="threads",collector="server"} 0
+# HELP ds_exporter_attribute_parse_errors_total Number of attribute values that could not be converted to a metric value
+# TYPE ds_exporter_attribute_parse_errors_total counter
+ds_exporter_attribute_parse_errors_total{attribute="currenttime",collector="server",type="time"} 2
+ds_exporter_attribute_parse_errors_total{attribute="threads",collector="server",type="float"} 2
+# HELP ds_exporter_entry_missing Whether the LDAP search of a collector returned no entry
+# TYPE ds_exporter_entry_missing gauge
+ds_exporter_entry_missing{collector="server",dn="cn=monitor"} 0
+ds_exporter_entry_missing{collector="snmp-server",dn="cn=snmp,cn=monitor"} 1
+`
+	gatherer := ds.Gatherer(context.Background())
+	_, err := gatherer.Gather()
+	require.NoError(t, err)
+	err = testutil.GatherAndCompare(gatherer, strings.NewReader(expected),
+		"ds_exporter_attribute_missing", "ds_exporter_attribute_parse_errors_total", "ds_exporter_entry_missing")
+	require.NoError(t, err, "Parse errors should be counted across scrapes")
+}
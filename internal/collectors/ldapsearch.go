@@ -0,0 +1,74 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// LDAPSearchCollector collects the statistics of the LDAP searches issued by the exporter, by collector.
+// Like LDAPStateCollector, it reads the pool on every collect and is registered in the main registry,
+// so that the statistics do not depend on the enabled collectors and are never cached.
+type LDAPSearchCollector struct {
+	connectionPool *expldap.Pool
+	descTime       *prometheus.Desc
+	descEntries    *prometheus.Desc
+	descResults    *prometheus.Desc
+}
+
+// NewLDAPSearchCollector creates the LDAPSearchCollector of the pool.
+func NewLDAPSearchCollector(connectionPool *expldap.Pool) *LDAPSearchCollector {
+	return &LDAPSearchCollector{
+		connectionPool: connectionPool,
+		descTime: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_ldap", "search_duration_seconds"),
+			"Duration of the LDAP searches issued by the exporter, by collector.",
+			[]string{"collector"},
+			nil,
+		),
+		descEntries: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_ldap", "search_entries_total"),
+			"Number of entries returned by the LDAP searches issued by the exporter, by collector.",
+			[]string{"collector"},
+			nil,
+		),
+		descResults: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_ldap", "searches_total"),
+			"Number of LDAP searches issued by the exporter, by collector and LDAP result.",
+			[]string{"collector", "result"},
+			nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of the metrics to the provided channel.
+func (c *LDAPSearchCollector) Describe(channel chan<- *prometheus.Desc) {
+	channel <- c.descTime
+	channel <- c.descEntries
+	channel <- c.descResults
+}
+
+// Collect sends the current search statistics of the pool to the provided channel.
+func (c *LDAPSearchCollector) Collect(channel chan<- prometheus.Metric) {
+	for collector, search := range c.connectionPool.Searches() {
+		channel <- prometheus.MustNewConstHistogram(
+			c.descTime,
+			search.Duration.Count,
+			search.Duration.Sum,
+			search.Duration.Buckets,
+			collector)
+		channel <- prometheus.MustNewConstMetric(
+			c.descEntries,
+			prometheus.CounterValue,
+			float64(search.Entries),
+			collector)
+		for code, count := range search.Results {
+			channel <- prometheus.MustNewConstMetric(
+				c.descResults,
+				prometheus.CounterValue,
+				float64(count),
+				collector,
+				expldap.ResultName(code))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestLDAPSearchCollector(t *testing.T) {
+	directory := newFakeDirectory()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		Auth:           expldap.AuthConfig{URL: "ldap://localhost:389"},
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	collector := NewLDAPSearchCollector(pool)
+	require.Equal(t, 0, testutil.CollectAndCount(collector), "No searches should be exported before the first one")
+
+	search := func() {
+		ctx := expldap.WithCollector(context.Background(), "server")
+		conn, err := pool.Conn(ctx)
+		require.NoError(t, err)
+		_, err = conn.Search(ctx, ldap.NewSearchRequest("cn=monitor", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+			0, 0, false, "(objectClass=*)", []string{"threads"}, nil))
+		require.NoError(t, err)
+		conn.Close()
+	}
+	expected := func(count string) string {
+		return `
+# HELP ds_exporter_ldap_searches_total Number of LDAP searches issued by the exporter, by collector and LDAP result.
+# TYPE ds_exporter_ldap_searches_total counter
+ds_exporter_ldap_searches_total{collector="server",result="success"} ` + count + `
+`
+	}
+
+	search()
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected("1")),
+		"ds_exporter_ldap_searches_total"))
+	search()
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected("2")),
+		"ds_exporter_ldap_searches_total"), "The statistics should be read from the pool on every collect")
+	require.Equal(t, 3, testutil.CollectAndCount(collector))
+}
@@ -0,0 +1,57 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// LDAPStateCollector collects the state of the LDAP connection: the circuit breaker and the active server URL.
+// It reads the pool on every collect and is registered in the main registry rather than in the DSCollector,
+// so that the state is exported even if the exporter-pool collector is disabled and is never cached.
+type LDAPStateCollector struct {
+	connectionPool *expldap.Pool
+	descEndpoint   *prometheus.Desc
+	descCircuit    *prometheus.Desc
+}
+
+// NewLDAPStateCollector creates the LDAPStateCollector of the pool.
+func NewLDAPStateCollector(connectionPool *expldap.Pool) *LDAPStateCollector {
+	return &LDAPStateCollector{
+		connectionPool: connectionPool,
+		descEndpoint: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_ldap", "active_endpoint"),
+			"Whether the LDAP server URL served the last successful operation.",
+			[]string{"url"},
+			nil,
+		),
+		descCircuit: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "exporter_ldap", "circuit_state"),
+			"State of the LDAP circuit breaker: 0 - closed, 1 - open, 2 - half-open.",
+			nil,
+			nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of the metrics to the provided channel.
+func (c *LDAPStateCollector) Describe(channel chan<- *prometheus.Desc) {
+	channel <- c.descEndpoint
+	channel <- c.descCircuit
+}
+
+// Collect sends the current state of the pool to the provided channel.
+func (c *LDAPStateCollector) Collect(channel chan<- prometheus.Metric) {
+	channel <- prometheus.MustNewConstMetric(
+		c.descCircuit,
+		prometheus.GaugeValue,
+		float64(c.connectionPool.CircuitState()))
+
+	for _, endpoint := range c.connectionPool.Endpoints() {
+		active := 0.0
+		if endpoint.Active {
+			active = 1
+		}
+		channel <- prometheus.MustNewConstMetric(c.descEndpoint, prometheus.GaugeValue, active, endpoint.URL)
+	}
+}
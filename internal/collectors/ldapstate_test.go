@@ -0,0 +1,48 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestLDAPStateCollector(t *testing.T) {
+	directory := newFakeDirectory()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		URLs: []string{"ldap://primary:389", "ldap://replica:389"},
+		ConnFactory: func(auth *expldap.AuthConfig) (expldap.Conn, error) {
+			if auth.URL == "ldap://primary:389" {
+				return nil, errors.New("connection refused")
+			}
+			return directory, nil
+		},
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	collector := NewLDAPStateCollector(pool)
+	expected := func(primary string, replica string) string {
+		return `
+# HELP ds_exporter_ldap_active_endpoint Whether the LDAP server URL served the last successful operation.
+# TYPE ds_exporter_ldap_active_endpoint gauge
+ds_exporter_ldap_active_endpoint{url="ldap://primary:389"} ` + primary + `
+ds_exporter_ldap_active_endpoint{url="ldap://replica:389"} ` + replica + `
+# HELP ds_exporter_ldap_circuit_state State of the LDAP circuit breaker: 0 - closed, 1 - open, 2 - half-open.
+# TYPE ds_exporter_ldap_circuit_state gauge
+ds_exporter_ldap_circuit_state 0
+`
+	}
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected("0", "0"))))
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	conn.Close()
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected("0", "1"))),
+		"The active endpoint should be read from the pool on every collect")
+}
@@ -1,6 +1,7 @@
 package collectors
 
 import (
+	"context"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,8 +15,17 @@ type PoolCollector struct {
 	descOpen         *prometheus.Desc
 	descClosedIdle   *prometheus.Desc
 	descClosedLife   *prometheus.Desc
+	descClosedCheck  *prometheus.Desc
 	descWaitCount    *prometheus.Desc
 	descWaitDuration *prometheus.Desc
+	descInUse        *prometheus.Desc
+	descIdle         *prometheus.Desc
+	descAcquireTime  *prometheus.Desc
+	descDialTime     *prometheus.Desc
+	descBindTime     *prometheus.Desc
+	descDialErrors   *prometheus.Desc
+	descBindErrors   *prometheus.Desc
+	descBadConns     *prometheus.Desc
 	mutex            sync.Mutex
 }
 
@@ -47,6 +57,13 @@ func NewPoolCollector(
 		labels,
 	)
 
+	pool.descClosedCheck = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "closed_liveness"),
+		"Number of idle connections closed after a failed liveness check.",
+		nil,
+		labels,
+	)
+
 	pool.descWaitCount = prometheus.NewDesc(
 		prometheus.BuildFQName(exporterNamespace, subsystem, "wait_count_total"),
 		"The number of times clients waited for a connection to appear in the pool.",
@@ -61,11 +78,67 @@ func NewPoolCollector(
 		labels,
 	)
 
+	pool.descInUse = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "in_use"),
+		"Number of connections currently in use.",
+		nil,
+		labels,
+	)
+
+	pool.descIdle = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "idle"),
+		"Number of idle connections in the pool.",
+		nil,
+		labels,
+	)
+
+	pool.descAcquireTime = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "acquire_duration_seconds"),
+		"Time spent acquiring a connection from the pool, including waiting, dialing and binding.",
+		nil,
+		labels,
+	)
+
+	pool.descDialTime = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "dial_duration_seconds"),
+		"Time spent dialing the LDAP server.",
+		nil,
+		labels,
+	)
+
+	pool.descBindTime = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "bind_duration_seconds"),
+		"Time spent binding new connections.",
+		nil,
+		labels,
+	)
+
+	pool.descDialErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "dial_errors_total"),
+		"Number of failed dials by LDAP result.",
+		[]string{"result"},
+		labels,
+	)
+
+	pool.descBindErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "bind_errors_total"),
+		"Number of failed binds by LDAP result.",
+		[]string{"result"},
+		labels,
+	)
+
+	pool.descBadConns = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, subsystem, "bad_conn_discards_total"),
+		"Number of broken or expired connections discarded while acquiring a connection.",
+		nil,
+		labels,
+	)
+
 	return pool
 }
 
 // Get function fetches metrics from LDAP and sends them to the provided channel.
-func (c *PoolCollector) Get(channel chan<- prometheus.Metric) error {
+func (c *PoolCollector) Get(_ context.Context, channel chan<- prometheus.Metric) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -74,11 +147,42 @@ func (c *PoolCollector) Get(channel chan<- prometheus.Metric) error {
 	channel <- prometheus.MustNewConstMetric(c.descOpen, prometheus.GaugeValue, float64(stat.Open))
 	channel <- prometheus.MustNewConstMetric(c.descClosedIdle, prometheus.CounterValue, float64(stat.ClosedIdleTime))
 	channel <- prometheus.MustNewConstMetric(c.descClosedLife, prometheus.CounterValue, float64(stat.ClosedLifeTime))
+	channel <- prometheus.MustNewConstMetric(
+		c.descClosedCheck,
+		prometheus.CounterValue,
+		float64(stat.ClosedLiveness))
 	channel <- prometheus.MustNewConstMetric(c.descWaitCount, prometheus.CounterValue, float64(stat.WaitCount))
 	channel <- prometheus.MustNewConstMetric(
 		c.descWaitDuration,
 		prometheus.CounterValue,
 		float64(stat.WaitDuration.Seconds()),
 	)
+	channel <- prometheus.MustNewConstMetric(c.descInUse, prometheus.GaugeValue, float64(stat.InUse))
+	channel <- prometheus.MustNewConstMetric(c.descIdle, prometheus.GaugeValue, float64(stat.Idle))
+	channel <- prometheus.MustNewConstMetric(c.descBadConns, prometheus.CounterValue, float64(stat.BadConnDiscards))
+
+	for desc, histogram := range map[*prometheus.Desc]expldap.HistogramStat{
+		c.descAcquireTime: stat.AcquireTime,
+		c.descDialTime:    stat.DialTime,
+		c.descBindTime:    stat.BindTime,
+	} {
+		channel <- prometheus.MustNewConstHistogram(desc, histogram.Count, histogram.Sum, histogram.Buckets)
+	}
+
+	for code, count := range stat.DialErrors {
+		channel <- prometheus.MustNewConstMetric(
+			c.descDialErrors,
+			prometheus.CounterValue,
+			float64(count),
+			expldap.ResultName(code))
+	}
+	for code, count := range stat.BindErrors {
+		channel <- prometheus.MustNewConstMetric(
+			c.descBindErrors,
+			prometheus.CounterValue,
+			float64(count),
+			expldap.ResultName(code))
+	}
+
 	return nil
 }
@@ -0,0 +1,75 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestPoolCollector(t *testing.T) {
+	directory := newFakeDirectory()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		Auth:           expldap.AuthConfig{URL: "ldap://localhost:389"},
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 2,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ctx := expldap.WithCollector(context.Background(), "server")
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	_, err = conn.Search(ctx, ldap.NewSearchRequest("cn=monitor", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", []string{"threads"}, nil))
+	require.NoError(t, err)
+	conn.Close()
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("exporter-pool", NewPoolCollector("exporter_pool", pool, prometheus.Labels{}))
+
+	samples := gather(t, ds)
+	for _, name := range []string{
+		"ds_exporter_pool_in_use",
+		"ds_exporter_pool_idle",
+		"ds_exporter_pool_acquire_duration_seconds",
+		"ds_exporter_pool_dial_duration_seconds",
+		"ds_exporter_pool_bind_duration_seconds",
+		"ds_exporter_pool_bad_conn_discards_total",
+	} {
+		require.Equal(t, 1, samples[name], "Pool collector should export %s", name)
+	}
+}
+
+func TestPoolCollectorErrorsByResult(t *testing.T) {
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory: func(_ *expldap.AuthConfig) (expldap.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+	_, err := pool.Conn(context.Background())
+	require.Error(t, err)
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("exporter-pool", NewPoolCollector("exporter_pool", pool, prometheus.Labels{}))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(ds)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	index := slices.IndexFunc(families, func(family *dto.MetricFamily) bool {
+		return family.GetName() == "ds_exporter_pool_dial_errors_total"
+	})
+	require.GreaterOrEqual(t, index, 0, "Dial errors should be exported")
+	labels := families[index].GetMetric()[0].GetLabel()
+	require.Equal(t, "result", labels[0].GetName())
+	require.Equal(t, "networkError", labels[0].GetValue(), "Dial errors should be labeled by result name")
+}
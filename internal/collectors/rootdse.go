@@ -0,0 +1,109 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// rootDSEAttributes returns the root DSE attributes read by the RootDSECollector.
+// The operational attributes of the root DSE are only returned when requested explicitly.
+func rootDSEAttributes() []string {
+	return []string{
+		"vendorName",
+		"vendorVersion",
+		"netscapemdsuffix",
+		"namingContexts",
+		"supportedSASLMechanisms",
+		"supportedLDAPVersion",
+	}
+}
+
+// RootDSECollector collects the server identity and capabilities published in the root DSE.
+type RootDSECollector struct {
+	connectionPool    *expldap.Pool
+	descServerInfo    *prometheus.Desc
+	descNamingContext *prometheus.Desc
+	descSASLMechanism *prometheus.Desc
+	descLDAPVersion   *prometheus.Desc
+	mutex             sync.Mutex
+	poolGetTimeout    time.Duration
+}
+
+// NewRootDSECollector function create new RootDSECollector instance based on provided parameters.
+func NewRootDSECollector(
+	connectionPool *expldap.Pool,
+	labels prometheus.Labels,
+	poolGetTimeout time.Duration,
+) *RootDSECollector {
+	return &RootDSECollector{
+		connectionPool: connectionPool,
+		descServerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "server", "info"),
+			"Directory server vendor, version and monitor suffix from the root DSE.",
+			[]string{"vendor_name", "vendor_version", "netscapemdsuffix"},
+			labels,
+		),
+		descNamingContext: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "naming_context", "info"),
+			"Naming context (suffix) served by the directory server.",
+			[]string{"suffix"},
+			labels,
+		),
+		descSASLMechanism: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "server", "supported_sasl_mechanism_info"),
+			"SASL mechanism supported by the directory server.",
+			[]string{"mechanism"},
+			labels,
+		),
+		descLDAPVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "server", "supported_ldap_version_info"),
+			"LDAP protocol version supported by the directory server.",
+			[]string{"version"},
+			labels,
+		),
+		poolGetTimeout: poolGetTimeout,
+	}
+}
+
+// Get function fetches the root DSE and sends the info metrics to the provided channel.
+func (c *RootDSECollector) Get(ctx context.Context, channel chan<- prometheus.Metric) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, err := searchBaseObject(ctx, c.connectionPool, c.poolGetTimeout, "", rootDSEAttributes())
+	if err != nil {
+		return fmt.Errorf("error getting root DSE from LDAP: %w", err)
+	}
+
+	if entry == nil {
+		slog.Warn("LDAP request for the root DSE returned no entries. The user may not have permissions")
+		return nil
+	}
+
+	channel <- prometheus.MustNewConstMetric(c.descServerInfo, prometheus.GaugeValue, 1,
+		entry.GetEqualFoldAttributeValue("vendorName"),
+		entry.GetEqualFoldAttributeValue("vendorVersion"),
+		entry.GetEqualFoldAttributeValue("netscapemdsuffix"),
+	)
+
+	for _, suffix := range entry.GetEqualFoldAttributeValues("namingContexts") {
+		channel <- prometheus.MustNewConstMetric(c.descNamingContext, prometheus.GaugeValue, 1, suffix)
+	}
+
+	for _, mechanism := range entry.GetEqualFoldAttributeValues("supportedSASLMechanisms") {
+		channel <- prometheus.MustNewConstMetric(c.descSASLMechanism, prometheus.GaugeValue, 1, mechanism)
+	}
+
+	for _, version := range entry.GetEqualFoldAttributeValues("supportedLDAPVersion") {
+		channel <- prometheus.MustNewConstMetric(c.descLDAPVersion, prometheus.GaugeValue, 1, version)
+	}
+
+	return nil
+}
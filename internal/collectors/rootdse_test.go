@@ -0,0 +1,62 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func TestRootDSECollector(t *testing.T) {
+	directory := &fakeDirectory{
+		entries: map[string]map[string][]string{
+			"": {
+				"vendorName":              {"389 Project"},
+				"vendorVersion":           {"389-Directory/2.4.5 B2024.017.0000"},
+				"netscapemdsuffix":        {"cn=ldap://dc=ds,dc=example,dc=com:389"},
+				"namingContexts":          {"dc=example,dc=com", "o=ipaca"},
+				"supportedSASLMechanisms": {"EXTERNAL", "GSSAPI"},
+				"supportedLDAPVersion":    {"2", "3"},
+			},
+		},
+	}
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("rootdse", NewRootDSECollector(pool, prometheus.Labels{}, time.Second))
+
+	expected := `
+# HELP ds_naming_context_info Naming context (suffix) served by the directory server.
+# TYPE ds_naming_context_info gauge
+ds_naming_context_info{suffix="dc=example,dc=com"} 1
+ds_naming_context_info{suffix="o=ipaca"} 1
+# HELP ds_server_info Directory server vendor, version and monitor suffix from the root DSE.
+# TYPE ds_server_info gauge
+ds_server_info{netscapemdsuffix="cn=ldap://dc=ds,dc=example,dc=com:389",vendor_name="389 Project",vendor_version="389-Directory/2.4.5 B2024.017.0000"} 1
+# HELP ds_server_supported_ldap_version_info LDAP protocol version supported by the directory server.
+# TYPE ds_server_supported_ldap_version_info gauge
+ds_server_supported_ldap_version_info{version="2"} 1
+ds_server_supported_ldap_version_info{version="3"} 1
+# HELP ds_server_supported_sasl_mechanism_info SASL mechanism supported by the directory server.
+# TYPE ds_server_supported_sasl_mechanism_info gauge
+ds_server_supported_sasl_mechanism_info{mechanism="EXTERNAL"} 1
+ds_server_supported_sasl_mechanism_info{mechanism="GSSAPI"} 1
+`
+	err := testutil.GatherAndCompare(ds.Gatherer(context.Background()), strings.NewReader(expected),
+		"ds_server_info",
+		"ds_naming_context_info",
+		"ds_server_supported_sasl_mechanism_info",
+		"ds_server_supported_ldap_version_info",
+	)
+	require.NoError(t, err)
+}
@@ -0,0 +1,228 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// subtreeEntryCollector is implemented by collectors that read a single LDAP entry,
+// which can be fetched by a subtree search instead of a base-object search.
+type subtreeEntryCollector interface {
+	entryDN() string
+	entryAttributes() []string
+}
+
+// SubtreeSearcher fetches the entries of several collectors with one subtree search per base DN
+// instead of one base-object search per collector.
+type SubtreeSearcher struct {
+	connectionPool *expldap.Pool
+	bases          []string
+	poolGetTimeout time.Duration
+}
+
+// NewSubtreeSearcher function create new SubtreeSearcher instance based on provided parameters.
+func NewSubtreeSearcher(connectionPool *expldap.Pool, bases []string, poolGetTimeout time.Duration) *SubtreeSearcher {
+	return &SubtreeSearcher{
+		connectionPool: connectionPool,
+		bases:          bases,
+		poolGetTimeout: poolGetTimeout,
+	}
+}
+
+// subtreeSearch describes a single subtree search and stores its result.
+type subtreeSearch struct {
+	base       string
+	filters    []string
+	attributes []string
+
+	once    sync.Once
+	entries map[string]*ldap.Entry // by normalized DN
+	err     error
+}
+
+// subtreeScrape stores the subtree searches of a single scrape.
+type subtreeScrape struct {
+	searcher *SubtreeSearcher
+	searches map[string]*subtreeSearch // by normalized entry DN
+	done     atomic.Int64              // number of subtree searches performed
+	served   atomic.Int64              // number of entries served from subtree searches
+}
+
+type subtreeScrapeKey struct{}
+
+// prepare plans the subtree searches for the given collectors, which must be only the collectors
+// going to query LDAP in the scrape.
+// The search filter matches the first RDN of every entry, so that the search does not return
+// the whole subtree. A subtree search is only planned for a base with at least two collector
+// entries below it, otherwise it would not save anything.
+func (s *SubtreeSearcher) prepare(collectors []InternalCollector) *subtreeScrape {
+	scrape := &subtreeScrape{
+		searcher: s,
+		searches: make(map[string]*subtreeSearch),
+	}
+
+	planned := make(map[string]*subtreeSearch)
+	entries := make(map[string][]string)
+	for _, collector := range collectors {
+		entryCollector, ok := collector.(subtreeEntryCollector)
+		if !ok {
+			continue
+		}
+		dn, err := ldap.ParseDN(entryCollector.entryDN())
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		for _, base := range s.bases {
+			baseDN, err := ldap.ParseDN(base)
+			if err != nil || (!baseDN.EqualFold(dn) && !baseDN.AncestorOfFold(dn)) {
+				continue
+			}
+			search, ok := planned[base]
+			if !ok {
+				search = &subtreeSearch{base: base}
+				planned[base] = search
+			}
+			rdn := dn.RDNs[0].Attributes[0]
+			search.filters = appendUnique(search.filters, fmt.Sprintf("(%s=%s)", rdn.Type, ldap.EscapeFilter(rdn.Value)))
+			for _, attr := range entryCollector.entryAttributes() {
+				search.attributes = appendUnique(search.attributes, attr)
+			}
+			entries[base] = append(entries[base], normalizeDN(dn))
+			break
+		}
+	}
+
+	for base, search := range planned {
+		if len(entries[base]) < 2 {
+			continue
+		}
+		for _, dn := range entries[base] {
+			scrape.searches[dn] = search
+		}
+	}
+
+	return scrape
+}
+
+// saved returns the number of LDAP searches saved by the scrape.
+// A failed subtree search costs one extra search, which is not reported as a negative saving.
+func (s *subtreeScrape) saved() int64 {
+	return max(s.served.Load()-s.done.Load(), 0)
+}
+
+// entry returns the entry with the given DN fetched by a subtree search.
+// The search is performed by the first collector asking for one of its entries.
+// If the entry is not covered by a subtree search or the search failed, ok is false
+// and the collector should fall back to its own search.
+func (s *subtreeScrape) entry(ctx context.Context, entryDN string) (*ldap.Entry, bool) {
+	dn, err := ldap.ParseDN(entryDN)
+	if err != nil {
+		return nil, false
+	}
+	key := normalizeDN(dn)
+	search, ok := s.searches[key]
+	if !ok {
+		return nil, false
+	}
+
+	search.once.Do(func() {
+		s.done.Add(1)
+		// the search serves several collectors, so it is not attributed to the one performing it
+		search.entries, search.err = s.searcher.search(expldap.WithCollector(ctx, "subtree"), search)
+		if search.err != nil {
+			slog.Warn("Subtree search failed, falling back to per-collector searches. "+
+				"Disable collectors_subtree_search if the ACIs do not allow subtree reads",
+				"base", search.base, "err", search.err)
+		}
+	})
+
+	if search.err != nil {
+		return nil, false
+	}
+
+	s.served.Add(1)
+	return search.entries[key], true
+}
+
+// search performs the subtree search and returns the found entries by normalized DN.
+func (s *SubtreeSearcher) search(ctx context.Context, search *subtreeSearch) (map[string]*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		search.base,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(|"+strings.Join(search.filters, "")+")",
+		search.attributes,
+		nil,
+	)
+
+	connCtx, cancel := context.WithTimeout(ctx, s.poolGetTimeout)
+	defer cancel()
+	conn, err := s.connectionPool.Conn(connCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
+	}
+	defer conn.Close()
+
+	searchResult, err := conn.Search(ctx, searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"LDAP subtree search request (dn='%v', filter='%v') failed with error: %w",
+			searchRequest.BaseDN,
+			searchRequest.Filter,
+			err,
+		)
+	}
+
+	entries := make(map[string]*ldap.Entry, len(searchResult.Entries))
+	for _, entry := range searchResult.Entries {
+		dn, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			slog.Debug("Skipping entry with invalid DN", "dn", entry.DN, "err", err)
+			continue
+		}
+		entries[normalizeDN(dn)] = entry
+	}
+
+	return entries, nil
+}
+
+// withSubtreeScrape returns a copy of ctx carrying the subtree scrape.
+func withSubtreeScrape(ctx context.Context, scrape *subtreeScrape) context.Context {
+	return context.WithValue(ctx, subtreeScrapeKey{}, scrape)
+}
+
+// subtreeEntry returns the entry with the given DN if it was fetched by a subtree search of the current scrape.
+func subtreeEntry(ctx context.Context, dn string) (*ldap.Entry, bool) {
+	scrape, ok := ctx.Value(subtreeScrapeKey{}).(*subtreeScrape)
+	if !ok {
+		return nil, false
+	}
+	return scrape.entry(ctx, dn)
+}
+
+// normalizeDN returns the DN in a form suitable for case-insensitive comparison.
+func normalizeDN(dn *ldap.DN) string {
+	return strings.ToLower(dn.String())
+}
+
+// appendUnique appends the value to the slice if it is not there yet, ignoring case.
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
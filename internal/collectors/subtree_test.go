@@ -0,0 +1,161 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// fakeDirectory is an in-memory LDAP connection serving a fixed set of entries.
+type fakeDirectory struct {
+	mu          sync.Mutex
+	entries     map[string]map[string][]string
+	searches    []*ldap.SearchRequest
+	denySubtree bool
+}
+
+func (f *fakeDirectory) Bind(_ expldap.AuthConfig) error { return nil }
+func (f *fakeDirectory) Unbind() error                   { return nil }
+func (f *fakeDirectory) Close() error                    { return nil }
+
+func (f *fakeDirectory) Search(_ context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searches = append(f.searches, req)
+
+	if req.Scope == ldap.ScopeWholeSubtree && f.denySubtree {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("insufficient access"))
+	}
+
+	result := &ldap.SearchResult{}
+	for dn, attrs := range f.entries {
+		if req.Scope == ldap.ScopeBaseObject && !strings.EqualFold(dn, req.BaseDN) {
+			continue
+		}
+		if req.Scope == ldap.ScopeWholeSubtree && !strings.HasSuffix(strings.ToLower(dn), strings.ToLower(req.BaseDN)) {
+			continue
+		}
+		entry := ldap.NewEntry(dn, nil)
+		for _, name := range req.Attributes {
+			// attribute subtypes, such as lastusn;userroot, are returned for the requested type
+			for _, attr := range slices.Sorted(maps.Keys(attrs)) {
+				if attr == name || strings.HasPrefix(attr, name+";") {
+					entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(attr, attrs[attr]))
+				}
+			}
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+func (f *fakeDirectory) searchCount(scope int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, req := range f.searches {
+		if req.Scope == scope {
+			count++
+		}
+	}
+	return count
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		entries: map[string]map[string][]string{
+			"cn=monitor":                  {"threads": {"16"}},
+			"cn=snmp,cn=monitor":          {"bindsecurityerrors": {"3"}},
+			"ou=people,dc=example,dc=com": {"numsubordinates": {"42"}},
+		},
+	}
+}
+
+func setupSubtreeCollector(t *testing.T, directory *fakeDirectory, cfg DSCollectorConfig) *DSCollector {
+	t.Helper()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 4,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	cfg.Subtree = NewSubtreeSearcher(pool, []string{"cn=monitor"}, time.Second)
+	ds := NewDSCollector(cfg)
+	ds.Register("server", NewLdapEntryCollector("server", pool, "cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"threads": {LdapName: "threads", Type: prometheus.GaugeValue},
+		}, nil, time.Second))
+	ds.Register("snmp-server", NewLdapEntryCollector("snmp_server", pool, "cn=snmp,cn=monitor",
+		map[string]LdapMonitoredAttribute{
+			"bind_security_errors_total": {LdapName: "bindsecurityerrors", Type: prometheus.CounterValue},
+		}, nil, time.Second))
+	ds.Register("numsubordinates", NewLdapEntryCollector("numsubordinates", pool, "ou=people,dc=example,dc=com",
+		map[string]LdapMonitoredAttribute{
+			"count": {LdapName: "numsubordinates", Type: prometheus.GaugeValue},
+		}, nil, time.Second))
+	return ds
+}
+
+func TestSubtreeSearch(t *testing.T) {
+	directory := newFakeDirectory()
+	ds := setupSubtreeCollector(t, directory, DSCollectorConfig{})
+
+	samples := gather(t, ds)
+	require.Equal(t, 1, samples["ds_server_threads"])
+	require.Equal(t, 1, samples["ds_snmp_server_bind_security_errors_total"])
+	require.Equal(t, 1, samples["ds_numsubordinates_count"])
+	require.Equal(t, 1, samples["ds_exporter_subtree_searches_saved"])
+
+	require.Equal(t, 1, directory.searchCount(ldap.ScopeWholeSubtree), "Entries under cn=monitor should be read at once")
+	require.Equal(t, 1, directory.searchCount(ldap.ScopeBaseObject), "Entries outside of the bases should be read separately")
+}
+
+func TestSubtreeSearchFallback(t *testing.T) {
+	directory := newFakeDirectory()
+	directory.denySubtree = true
+	ds := setupSubtreeCollector(t, directory, DSCollectorConfig{})
+
+	samples := gather(t, ds)
+	require.Equal(t, 1, samples["ds_server_threads"], "Collectors should fall back to own searches")
+	require.Equal(t, 1, samples["ds_snmp_server_bind_security_errors_total"], "Collectors should fall back to own searches")
+	require.Equal(t, 3, directory.searchCount(ldap.ScopeBaseObject))
+}
+
+func TestSubtreeSearchCachedCollectors(t *testing.T) {
+	directory := newFakeDirectory()
+	ds := setupSubtreeCollector(t, directory, DSCollectorConfig{MinInterval: time.Hour})
+
+	samples := gather(t, ds)
+	require.Equal(t, 1, samples["ds_exporter_subtree_searches_saved"])
+	require.Equal(t, 1, directory.searchCount(ldap.ScopeWholeSubtree))
+
+	// only the server collector is due, the snmp-server result is still cached
+	state := ds.collectors["server"]
+	state.Lock()
+	state.last.time = time.Now().Add(-2 * time.Hour)
+	state.Unlock()
+
+	expected := `
+# HELP ds_exporter_subtree_searches_saved Number of LDAP searches saved by the subtree searches during the scrape
+# TYPE ds_exporter_subtree_searches_saved gauge
+ds_exporter_subtree_searches_saved 0
+`
+	err := testutil.GatherAndCompare(ds.Gatherer(context.Background()), strings.NewReader(expected),
+		"ds_exporter_subtree_searches_saved")
+	require.NoError(t, err, "A single due entry should not be reported as a saving")
+	require.Equal(t, 1, directory.searchCount(ldap.ScopeWholeSubtree),
+		"A single due entry should be read without a subtree search")
+	require.Equal(t, 2, directory.searchCount(ldap.ScopeBaseObject))
+}
@@ -0,0 +1,108 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+const (
+	// lastUSNAttribute is the root DSE attribute published by the USN plugin with a backend name subtype,
+	// or without a subtype in the global mode of the plugin.
+	lastUSNAttribute = "lastusn"
+
+	// globalUSNDatabase is the database label of the lastusn attribute of the global mode.
+	globalUSNDatabase = "global"
+)
+
+// UsnCollector collects the last update sequence number of every backend from the root DSE.
+type UsnCollector struct {
+	connectionPool *expldap.Pool
+	descLastUSN    *prometheus.Desc
+	mutex          sync.Mutex
+	poolGetTimeout time.Duration
+	disabledWarned bool
+}
+
+// NewUsnCollector function create new UsnCollector instance based on provided parameters.
+func NewUsnCollector(
+	connectionPool *expldap.Pool,
+	labels prometheus.Labels,
+	poolGetTimeout time.Duration,
+) *UsnCollector {
+	return &UsnCollector{
+		connectionPool: connectionPool,
+		descLastUSN: prometheus.NewDesc(
+			prometheus.BuildFQName(exporterNamespace, "backend", "last_usn"),
+			"The last update sequence number assigned by the backend database.",
+			[]string{"database"},
+			labels,
+		),
+		poolGetTimeout: poolGetTimeout,
+	}
+}
+
+// Get function fetches the lastusn values from the root DSE and sends them to the provided channel.
+func (c *UsnCollector) Get(ctx context.Context, channel chan<- prometheus.Metric) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, err := searchBaseObject(ctx, c.connectionPool, c.poolGetTimeout, "", []string{lastUSNAttribute})
+	if err != nil {
+		return fmt.Errorf("error getting root DSE from LDAP: %w", err)
+	}
+
+	found := false
+	if entry != nil {
+		for _, attr := range entry.Attributes {
+			database, ok := usnDatabase(attr.Name)
+			if !ok || len(attr.Values) == 0 {
+				continue
+			}
+			found = true
+
+			value, err := strconv.ParseFloat(attr.Values[0], 64)
+			if err != nil {
+				slog.Debug("Error converting attribute value to type float64",
+					"attr_name", attr.Name,
+					"attr_value", attr.Values[0])
+				return fmt.Errorf("error converting attribute value to float64: %w", err)
+			}
+			if value < 0 {
+				// The backend has not assigned any USN yet
+				continue
+			}
+
+			channel <- prometheus.MustNewConstMetric(c.descLastUSN, prometheus.CounterValue, value, database)
+		}
+	}
+
+	if !found && !c.disabledWarned {
+		slog.Warn("The root DSE has no lastusn attributes, the USN plugin is probably disabled. " +
+			"Enable the USN plugin or disable the usn collector")
+		c.disabledWarned = true
+	}
+	return nil
+}
+
+// usnDatabase returns the backend name from the option of a lastusn attribute name, e.g. "lastusn;userroot",
+// or global for the lastusn attribute without an option published in the global mode of the USN plugin.
+func usnDatabase(attributeName string) (string, bool) {
+	name, option, _ := strings.Cut(attributeName, ";")
+	if !strings.EqualFold(name, lastUSNAttribute) {
+		return "", false
+	}
+	if option == "" {
+		return globalUSNDatabase, true
+	}
+	database, _, _ := strings.Cut(option, ";")
+	return database, true
+}
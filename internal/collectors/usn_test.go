@@ -0,0 +1,76 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+func setupUsnCollector(t *testing.T, rootDSE map[string][]string) *DSCollector {
+	t.Helper()
+	directory := &fakeDirectory{entries: map[string]map[string][]string{"": rootDSE}}
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return directory, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ds := NewDSCollector(DSCollectorConfig{})
+	ds.Register("usn", NewUsnCollector(pool, prometheus.Labels{}, time.Second))
+	return ds
+}
+
+func TestUsnCollector(t *testing.T) {
+	ds := setupUsnCollector(t, map[string][]string{
+		"lastusn;userroot":  {"1234"},
+		"lastusn;ipaca":     {"56"},
+		"lastusn;changelog": {"-1"},
+		"vendorName":        {"389 Project"},
+	})
+
+	expected := `
+# HELP ds_backend_last_usn The last update sequence number assigned by the backend database.
+# TYPE ds_backend_last_usn counter
+ds_backend_last_usn{database="ipaca"} 56
+ds_backend_last_usn{database="userroot"} 1234
+`
+	err := testutil.GatherAndCompare(ds.Gatherer(context.Background()), strings.NewReader(expected),
+		"ds_backend_last_usn")
+	require.NoError(t, err)
+}
+
+func TestUsnCollectorGlobalMode(t *testing.T) {
+	ds := setupUsnCollector(t, map[string][]string{
+		"lastusn":    {"789"},
+		"vendorName": {"389 Project"},
+	})
+
+	expected := `
+# HELP ds_backend_last_usn The last update sequence number assigned by the backend database.
+# TYPE ds_backend_last_usn counter
+ds_backend_last_usn{database="global"} 789
+`
+	err := testutil.GatherAndCompare(ds.Gatherer(context.Background()), strings.NewReader(expected),
+		"ds_backend_last_usn")
+	require.NoError(t, err, "The lastusn attribute of the global mode should be exported")
+}
+
+func TestUsnDatabase(t *testing.T) {
+	database, ok := usnDatabase("lastUSN;userRoot")
+	require.True(t, ok)
+	require.Equal(t, "userRoot", database)
+
+	database, ok = usnDatabase("lastusn")
+	require.True(t, ok)
+	require.Equal(t, "global", database)
+
+	_, ok = usnDatabase("vendorName;x")
+	require.False(t, ok)
+}
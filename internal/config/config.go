@@ -3,9 +3,12 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"slices"
+	"strings"
 
+	"github.com/go-ldap/ldap/v3"
 	"gopkg.in/yaml.v2"
 )
 
@@ -21,19 +24,45 @@ var (
 )
 
 const (
-	defaultShutdownTimeout    int    = 5
-	defaultLDAPTlsSkipVerify  bool   = false
-	defaultLDAPPoolConnLimit  int    = 4
-	defaultLDAPPoolGetTimeout int    = 5
-	defaultLDAPPoolIdleTime   int    = 300
-	defaultLDAPPoolLifeTime   int    = 3600
-	defaultLDAPDialTimeout    int    = 3
-	defaultCollectorsDefault  string = "standard"
+	defaultShutdownTimeout              int     = 5
+	defaultLDAPTlsSkipVerify            bool    = false
+	defaultLDAPPoolConnLimit            int     = 4
+	defaultLDAPPoolGetTimeout           int     = 5
+	defaultLDAPPoolIdleTime             int     = 300
+	defaultLDAPPoolLifeTime             int     = 3600
+	defaultLDAPDialTimeout              int     = 3
+	defaultLDAPPoolMinIdle              int     = 0
+	defaultLDAPPoolIdleCheckInterval    int     = 0
+	defaultLDAPCircuitInitialBackoff    float64 = 1
+	defaultLDAPCircuitMaxBackoff        float64 = 60
+	defaultCollectorsDefault            string  = "standard"
+	defaultCollectorsMinInterval        int     = 0
+	defaultCollectorsBackgroundInterval int     = 0
+	defaultCollectorsStaleWindow        int     = 300
+	defaultCollectorsSubtreeSearch      bool    = false
+	defaultScrapeTimeoutOffset          float64 = 0.5
+	defaultDSDiscoveryInterval          int     = 300
+	defaultLDAPFailoverStrategy         string  = FailoverOrdered
+	defaultLDAPEndpointDemotionTime     int     = 60
+	defaultOTLPMetricsProtocol          string  = OTLPProtocolHTTP
+	defaultOTLPMetricsInterval          int     = 60
+	defaultRemoteWriteInterval          int     = 60
+	defaultRemoteWriteQueueSize         int     = 10
 
 	// BackendBDB corresponds to the Berkeley DB backend database.
 	BackendBDB string = "bdb"
 	// BackendMDB corresponds to the LMDB backend database.
 	BackendMDB string = "mdb"
+
+	// FailoverOrdered means that the LDAP server URLs are tried in the configured order.
+	FailoverOrdered string = "ordered"
+	// FailoverRoundRobin means that every new connection starts with the next LDAP server URL.
+	FailoverRoundRobin string = "round-robin"
+
+	// OTLPProtocolHTTP means that the metrics are pushed with OTLP/HTTP in the protobuf encoding.
+	OTLPProtocolHTTP string = "http/protobuf"
+	// OTLPProtocolGRPC means that the metrics are pushed with OTLP/gRPC.
+	OTLPProtocolGRPC string = "grpc"
 )
 
 // ExporterConfig is a structure representing the parsed configuration of the exporter.
@@ -41,41 +70,95 @@ type ExporterConfig struct {
 	// YAML tags are needed here for correct marshalling
 	// of the structure when it is necessary to display the final config
 
-	ShutdownTimeout         int      `yaml:"shutdown_timeout"`
-	CollectorsDefault       string   `yaml:"collectors_default"`
-	CollectorsEnabled       []string `yaml:"collectors_enabled"`
-	DSNumSubordinateRecords []string `yaml:"ds_numsubordinate_records"`
-	DSBackendType           string   `yaml:"ds_backend_type"`
-	DSBackendDBs            []string `yaml:"ds_backend_dbs"`
-
-	LDAPServerURL      string `yaml:"ldap_server_url"`
-	LDAPBindDN         string `yaml:"ldap_bind_dn"`
-	LDAPBindPw         string `yaml:"ldap_bind_pw"`
-	LDAPTlsSkipVerify  bool   `yaml:"ldap_tls_skip_verify"`
-	LDAPPoolConnLimit  int    `yaml:"ldap_pool_conn_limit"`
-	LDAPPoolGetTimeout int    `yaml:"ldap_pool_get_timeout"`
-	LDAPPoolIdleTime   int    `yaml:"ldap_pool_idle_time"`
-	LDAPPoolLifeTime   int    `yaml:"ldap_pool_life_time"`
-	LDAPDialTimeout    int    `yaml:"ldap_dial_timeout"`
+	ShutdownTimeout              int      `yaml:"shutdown_timeout"`
+	ScrapeTimeoutOffset          float64  `yaml:"scrape_timeout_offset"`
+	CollectorsDefault            string   `yaml:"collectors_default"`
+	CollectorsEnabled            []string `yaml:"collectors_enabled"`
+	CollectorsMinInterval        int      `yaml:"collectors_min_interval"`
+	CollectorsBackgroundInterval int      `yaml:"collectors_background_interval"`
+	CollectorsStaleWindow        int      `yaml:"collectors_stale_window"`
+	CollectorsSubtreeSearch      bool     `yaml:"collectors_subtree_search"`
+	DSNumSubordinateRecords      []string `yaml:"ds_numsubordinate_records"`
+	DSBackendType                string   `yaml:"ds_backend_type"`
+	DSBackendDBs                 []string `yaml:"ds_backend_dbs"`
+	DSDiscoveryInterval          int      `yaml:"ds_discovery_interval"`
+
+	LDAPServerURL             string   `yaml:"ldap_server_url"`
+	LDAPServerURLs            []string `yaml:"ldap_server_urls"`
+	LDAPFailoverStrategy      string   `yaml:"ldap_failover_strategy"`
+	LDAPEndpointDemotionTime  int      `yaml:"ldap_endpoint_demotion_time"`
+	LDAPBindDN                string   `yaml:"ldap_bind_dn"`
+	LDAPBindPw                string   `yaml:"ldap_bind_pw"`
+	LDAPTlsSkipVerify         bool     `yaml:"ldap_tls_skip_verify"`
+	LDAPPoolConnLimit         int      `yaml:"ldap_pool_conn_limit"`
+	LDAPPoolGetTimeout        int      `yaml:"ldap_pool_get_timeout"`
+	LDAPPoolIdleTime          int      `yaml:"ldap_pool_idle_time"`
+	LDAPPoolLifeTime          int      `yaml:"ldap_pool_life_time"`
+	LDAPPoolMinIdle           int      `yaml:"ldap_pool_min_idle"`
+	LDAPPoolIdleCheckInterval int      `yaml:"ldap_pool_idle_check_interval"`
+	LDAPDialTimeout           int      `yaml:"ldap_dial_timeout"`
+	LDAPCircuitInitialBackoff float64  `yaml:"ldap_circuit_initial_backoff"`
+	LDAPCircuitMaxBackoff     float64  `yaml:"ldap_circuit_max_backoff"`
+
+	OTLPMetricsEndpoint string   `yaml:"otlp_metrics_endpoint"`
+	OTLPMetricsProtocol string   `yaml:"otlp_metrics_protocol"`
+	OTLPMetricsInterval int      `yaml:"otlp_metrics_interval"`
+	OTLPMetricsHeaders  []string `yaml:"otlp_metrics_headers"`
+
+	RemoteWriteURL            string   `yaml:"remote_write_url"`
+	RemoteWriteInterval       int      `yaml:"remote_write_interval"`
+	RemoteWriteQueueSize      int      `yaml:"remote_write_queue_size"`
+	RemoteWriteUsername       string   `yaml:"remote_write_username"`
+	RemoteWritePassword       string   `yaml:"remote_write_password"`
+	RemoteWriteBearerToken    string   `yaml:"remote_write_bearer_token"`
+	RemoteWriteExternalLabels []string `yaml:"remote_write_external_labels"`
+
+	sources map[string]string // source of every value by YAML key
 }
 
 type rawConfig struct {
-	ShutdownTimeout         *int     `yaml:"shutdown_timeout"`
-	CollectorsDefault       *string  `yaml:"collectors_default"`
-	CollectorsEnabled       []string `yaml:"collectors_enabled"`
-	DSNumSubordinateRecords []string `yaml:"ds_numsubordinate_records"`
-	DSBackendType           *string  `yaml:"ds_backend_type"`
-	DSBackendDBs            []string `yaml:"ds_backend_dbs"`
-
-	LDAPServerURL      *string `yaml:"ldap_server_url"`
-	LDAPBindDN         *string `yaml:"ldap_bind_dn"`
-	LDAPBindPw         *string `yaml:"ldap_bind_pw"`
-	LDAPTlsSkipVerify  *bool   `yaml:"ldap_tls_skip_verify"`
-	LDAPPoolConnLimit  *int    `yaml:"ldap_pool_conn_limit"`
-	LDAPPoolGetTimeout *int    `yaml:"ldap_pool_get_timeout"`
-	LDAPPoolIdleTime   *int    `yaml:"ldap_pool_idle_time"`
-	LDAPPoolLifeTime   *int    `yaml:"ldap_pool_life_time"`
-	LDAPDialTimeout    *int    `yaml:"ldap_dial_timeout"`
+	ShutdownTimeout              *int     `yaml:"shutdown_timeout"`
+	ScrapeTimeoutOffset          *float64 `yaml:"scrape_timeout_offset"`
+	CollectorsDefault            *string  `yaml:"collectors_default"`
+	CollectorsEnabled            []string `yaml:"collectors_enabled"`
+	CollectorsMinInterval        *int     `yaml:"collectors_min_interval"`
+	CollectorsBackgroundInterval *int     `yaml:"collectors_background_interval"`
+	CollectorsStaleWindow        *int     `yaml:"collectors_stale_window"`
+	CollectorsSubtreeSearch      *bool    `yaml:"collectors_subtree_search"`
+	DSNumSubordinateRecords      []string `yaml:"ds_numsubordinate_records"`
+	DSBackendType                *string  `yaml:"ds_backend_type"`
+	DSBackendDBs                 []string `yaml:"ds_backend_dbs"`
+	DSDiscoveryInterval          *int     `yaml:"ds_discovery_interval"`
+
+	LDAPServerURL             *string  `yaml:"ldap_server_url"`
+	LDAPServerURLs            []string `yaml:"ldap_server_urls"`
+	LDAPFailoverStrategy      *string  `yaml:"ldap_failover_strategy"`
+	LDAPEndpointDemotionTime  *int     `yaml:"ldap_endpoint_demotion_time"`
+	LDAPBindDN                *string  `yaml:"ldap_bind_dn"`
+	LDAPBindPw                *string  `yaml:"ldap_bind_pw"`
+	LDAPTlsSkipVerify         *bool    `yaml:"ldap_tls_skip_verify"`
+	LDAPPoolConnLimit         *int     `yaml:"ldap_pool_conn_limit"`
+	LDAPPoolGetTimeout        *int     `yaml:"ldap_pool_get_timeout"`
+	LDAPPoolIdleTime          *int     `yaml:"ldap_pool_idle_time"`
+	LDAPPoolLifeTime          *int     `yaml:"ldap_pool_life_time"`
+	LDAPPoolMinIdle           *int     `yaml:"ldap_pool_min_idle"`
+	LDAPPoolIdleCheckInterval *int     `yaml:"ldap_pool_idle_check_interval"`
+	LDAPDialTimeout           *int     `yaml:"ldap_dial_timeout"`
+	LDAPCircuitInitialBackoff *float64 `yaml:"ldap_circuit_initial_backoff"`
+	LDAPCircuitMaxBackoff     *float64 `yaml:"ldap_circuit_max_backoff"`
+
+	OTLPMetricsEndpoint *string  `yaml:"otlp_metrics_endpoint"`
+	OTLPMetricsProtocol *string  `yaml:"otlp_metrics_protocol"`
+	OTLPMetricsInterval *int     `yaml:"otlp_metrics_interval"`
+	OTLPMetricsHeaders  []string `yaml:"otlp_metrics_headers"`
+
+	RemoteWriteURL            *string  `yaml:"remote_write_url"`
+	RemoteWriteInterval       *int     `yaml:"remote_write_interval"`
+	RemoteWriteQueueSize      *int     `yaml:"remote_write_queue_size"`
+	RemoteWriteUsername       *string  `yaml:"remote_write_username"`
+	RemoteWritePassword       *string  `yaml:"remote_write_password"`
+	RemoteWriteBearerToken    *string  `yaml:"remote_write_bearer_token"`
+	RemoteWriteExternalLabels []string `yaml:"remote_write_external_labels"`
 }
 
 func setDefaultIfNotDefined[T any](pointer *T, value *T, defaultValue T) {
@@ -91,9 +174,19 @@ func (r *rawConfig) toConfig() *ExporterConfig {
 
 	// Global
 	setDefaultIfNotDefined(r.ShutdownTimeout, &cfg.ShutdownTimeout, defaultShutdownTimeout)
+	setDefaultIfNotDefined(r.ScrapeTimeoutOffset, &cfg.ScrapeTimeoutOffset, defaultScrapeTimeoutOffset)
 	setDefaultIfNotDefined(r.CollectorsDefault, &cfg.CollectorsDefault, defaultCollectorsDefault)
+	setDefaultIfNotDefined(r.CollectorsMinInterval, &cfg.CollectorsMinInterval, defaultCollectorsMinInterval)
+	setDefaultIfNotDefined(
+		r.CollectorsBackgroundInterval,
+		&cfg.CollectorsBackgroundInterval,
+		defaultCollectorsBackgroundInterval,
+	)
+	setDefaultIfNotDefined(r.CollectorsStaleWindow, &cfg.CollectorsStaleWindow, defaultCollectorsStaleWindow)
+	setDefaultIfNotDefined(r.CollectorsSubtreeSearch, &cfg.CollectorsSubtreeSearch, defaultCollectorsSubtreeSearch)
 
 	setDefaultIfNotDefined(r.DSBackendType, &cfg.DSBackendType, "")
+	setDefaultIfNotDefined(r.DSDiscoveryInterval, &cfg.DSDiscoveryInterval, defaultDSDiscoveryInterval)
 
 	cfg.CollectorsEnabled = r.CollectorsEnabled
 	cfg.DSNumSubordinateRecords = r.DSNumSubordinateRecords
@@ -104,6 +197,13 @@ func (r *rawConfig) toConfig() *ExporterConfig {
 	if r.LDAPServerURL != nil {
 		cfg.LDAPServerURL = *r.LDAPServerURL
 	}
+	cfg.LDAPServerURLs = r.LDAPServerURLs
+	setDefaultIfNotDefined(r.LDAPFailoverStrategy, &cfg.LDAPFailoverStrategy, defaultLDAPFailoverStrategy)
+	setDefaultIfNotDefined(
+		r.LDAPEndpointDemotionTime,
+		&cfg.LDAPEndpointDemotionTime,
+		defaultLDAPEndpointDemotionTime,
+	)
 	if r.LDAPBindDN != nil {
 		cfg.LDAPBindDN = *r.LDAPBindDN
 	}
@@ -116,55 +216,397 @@ func (r *rawConfig) toConfig() *ExporterConfig {
 	setDefaultIfNotDefined(r.LDAPPoolGetTimeout, &cfg.LDAPPoolGetTimeout, defaultLDAPPoolGetTimeout)
 	setDefaultIfNotDefined(r.LDAPPoolIdleTime, &cfg.LDAPPoolIdleTime, defaultLDAPPoolIdleTime)
 	setDefaultIfNotDefined(r.LDAPPoolLifeTime, &cfg.LDAPPoolLifeTime, defaultLDAPPoolLifeTime)
+	setDefaultIfNotDefined(r.LDAPPoolMinIdle, &cfg.LDAPPoolMinIdle, defaultLDAPPoolMinIdle)
+	setDefaultIfNotDefined(
+		r.LDAPPoolIdleCheckInterval,
+		&cfg.LDAPPoolIdleCheckInterval,
+		defaultLDAPPoolIdleCheckInterval,
+	)
 	setDefaultIfNotDefined(r.LDAPDialTimeout, &cfg.LDAPDialTimeout, defaultLDAPDialTimeout)
+	setDefaultIfNotDefined(
+		r.LDAPCircuitInitialBackoff,
+		&cfg.LDAPCircuitInitialBackoff,
+		defaultLDAPCircuitInitialBackoff,
+	)
+	setDefaultIfNotDefined(r.LDAPCircuitMaxBackoff, &cfg.LDAPCircuitMaxBackoff, defaultLDAPCircuitMaxBackoff)
+
+	// OTLP
+	setDefaultIfNotDefined(r.OTLPMetricsEndpoint, &cfg.OTLPMetricsEndpoint, "")
+	setDefaultIfNotDefined(r.OTLPMetricsProtocol, &cfg.OTLPMetricsProtocol, defaultOTLPMetricsProtocol)
+	setDefaultIfNotDefined(r.OTLPMetricsInterval, &cfg.OTLPMetricsInterval, defaultOTLPMetricsInterval)
+	cfg.OTLPMetricsHeaders = r.OTLPMetricsHeaders
+
+	// Remote write
+	setDefaultIfNotDefined(r.RemoteWriteURL, &cfg.RemoteWriteURL, "")
+	setDefaultIfNotDefined(r.RemoteWriteInterval, &cfg.RemoteWriteInterval, defaultRemoteWriteInterval)
+	setDefaultIfNotDefined(r.RemoteWriteQueueSize, &cfg.RemoteWriteQueueSize, defaultRemoteWriteQueueSize)
+	setDefaultIfNotDefined(r.RemoteWriteUsername, &cfg.RemoteWriteUsername, "")
+	setDefaultIfNotDefined(r.RemoteWritePassword, &cfg.RemoteWritePassword, "")
+	setDefaultIfNotDefined(r.RemoteWriteBearerToken, &cfg.RemoteWriteBearerToken, "")
+	cfg.RemoteWriteExternalLabels = r.RemoteWriteExternalLabels
 
 	return cfg
 }
 
 // Validate function cheks if provided configuration is valid.
+// It returns all problems found joined into one error.
 func (c *ExporterConfig) Validate() error {
+	var errs []error
 
 	if c.ShutdownTimeout < 0 {
-		return fmt.Errorf("%w: shutdown_timeout should be greater than or equal to 0", ErrInvalidFieldValue)
+		errs = append(errs, fmt.Errorf("%w: shutdown_timeout should be greater than or equal to 0", ErrInvalidFieldValue))
+	}
+
+	if c.ScrapeTimeoutOffset < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: scrape_timeout_offset should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.CollectorsMinInterval < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: collectors_min_interval should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.CollectorsBackgroundInterval < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: collectors_background_interval should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.CollectorsStaleWindow < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: collectors_stale_window should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if !slices.Contains([]string{"all", "none", "standard"}, c.CollectorsDefault) {
+		errs = append(errs, fmt.Errorf(
+			"%w: invalid collectors_default: %s (must be 'all', 'none' or 'standard')",
+			ErrInvalidFieldValue,
+			c.CollectorsDefault,
+		))
 	}
 
 	// Also allow an empty value if the user wants the backend to be automatically detected.
 	if !slices.Contains([]string{BackendBDB, BackendMDB, ""}, c.DSBackendType) {
-		return fmt.Errorf(
+		errs = append(errs, fmt.Errorf(
 			"%w: invalid ds_backend_type: %s (must be 'bdb', 'mdb' or empty)",
 			ErrInvalidFieldValue,
 			c.DSBackendType,
-		)
+		))
+	}
+
+	if c.DSDiscoveryInterval < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: ds_discovery_interval should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.LDAPServerURL == "" && len(c.LDAPServerURLs) == 0 {
+		errs = append(errs, fmt.Errorf("ldap_server_url or ldap_server_urls: %w", ErrNoRequiredValue))
+	}
+
+	if c.LDAPServerURL != "" && len(c.LDAPServerURLs) > 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: ldap_server_url and ldap_server_urls cannot be specified together",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if !slices.Contains([]string{FailoverOrdered, FailoverRoundRobin}, c.LDAPFailoverStrategy) {
+		errs = append(errs, fmt.Errorf(
+			"%w: invalid ldap_failover_strategy: %s (must be 'ordered' or 'round-robin')",
+			ErrInvalidFieldValue,
+			c.LDAPFailoverStrategy,
+		))
 	}
 
-	if c.LDAPServerURL == "" {
-		return fmt.Errorf("ldap_server_url: %w", ErrNoRequiredValue)
+	if c.LDAPEndpointDemotionTime < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: ldap_endpoint_demotion_time should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
 	}
 
 	if c.LDAPBindDN == "" {
-		return fmt.Errorf("ldap_bind_dn: %w", ErrNoRequiredValue)
+		errs = append(errs, fmt.Errorf("ldap_bind_dn: %w", ErrNoRequiredValue))
 	}
 
 	if c.LDAPBindPw == "" {
-		return fmt.Errorf("ldap_bind_pw: %w", ErrNoRequiredValue)
+		errs = append(errs, fmt.Errorf("ldap_bind_pw: %w", ErrNoRequiredValue))
 	}
 
 	if c.LDAPPoolConnLimit <= 0 {
-		return fmt.Errorf("%w: invalid ldap_pool_conn_limit: must be greater than 0", ErrInvalidFieldValue)
+		errs = append(errs, fmt.Errorf("%w: invalid ldap_pool_conn_limit: must be greater than 0", ErrInvalidFieldValue))
 	}
 
 	if c.LDAPPoolGetTimeout <= 0 {
-		return fmt.Errorf("%w: invalid ldap_pool_get_timeout: must be greater than 0", ErrInvalidFieldValue)
+		errs = append(errs, fmt.Errorf("%w: invalid ldap_pool_get_timeout: must be greater than 0", ErrInvalidFieldValue))
+	}
+
+	if c.LDAPPoolMinIdle < 0 || c.LDAPPoolMinIdle > c.LDAPPoolConnLimit {
+		errs = append(errs, fmt.Errorf(
+			"%w: invalid ldap_pool_min_idle: must be between 0 and ldap_pool_conn_limit",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.LDAPPoolIdleCheckInterval < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: ldap_pool_idle_check_interval should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
 	}
 
 	if c.LDAPDialTimeout <= 0 {
-		return fmt.Errorf("%w: invalid ldap_dial_timeout: must be greater than 0", ErrInvalidFieldValue)
+		errs = append(errs, fmt.Errorf("%w: invalid ldap_dial_timeout: must be greater than 0", ErrInvalidFieldValue))
+	}
+
+	if c.LDAPCircuitInitialBackoff < 0 {
+		errs = append(errs, fmt.Errorf(
+			"%w: ldap_circuit_initial_backoff should be greater than or equal to 0",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.LDAPCircuitMaxBackoff < c.LDAPCircuitInitialBackoff {
+		errs = append(errs, fmt.Errorf(
+			"%w: ldap_circuit_max_backoff should be greater than or equal to ldap_circuit_initial_backoff",
+			ErrInvalidFieldValue,
+		))
 	}
 
+	if !slices.Contains([]string{OTLPProtocolHTTP, OTLPProtocolGRPC}, c.OTLPMetricsProtocol) {
+		errs = append(errs, fmt.Errorf(
+			"%w: invalid otlp_metrics_protocol: %s (must be 'http/protobuf' or 'grpc')",
+			ErrInvalidFieldValue,
+			c.OTLPMetricsProtocol,
+		))
+	}
+
+	if c.OTLPMetricsInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: invalid otlp_metrics_interval: must be greater than 0", ErrInvalidFieldValue))
+	}
+
+	errs = append(errs, c.validateRemoteWrite()...)
+	errs = append(errs, c.validateSemantics()...)
+
+	return errors.Join(errs...)
+}
+
+// validateRemoteWrite checks the remote_write_* parameters.
+func (c *ExporterConfig) validateRemoteWrite() []error {
+	var errs []error
+
+	if c.RemoteWriteURL != "" {
+		err := validatePushURL(c.RemoteWriteURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: invalid remote_write_url: %w", ErrInvalidFieldValue, err))
+		}
+	}
+
+	if c.RemoteWriteInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: invalid remote_write_interval: must be greater than 0", ErrInvalidFieldValue))
+	}
+
+	if c.RemoteWriteQueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("%w: invalid remote_write_queue_size: must be greater than 0", ErrInvalidFieldValue))
+	}
+
+	if c.RemoteWriteBearerToken != "" && c.RemoteWriteUsername != "" {
+		errs = append(errs, fmt.Errorf(
+			"%w: remote_write_bearer_token and remote_write_username cannot be specified together",
+			ErrInvalidFieldValue,
+		))
+	}
+
+	if c.RemoteWritePassword != "" && c.RemoteWriteUsername == "" {
+		errs = append(errs, fmt.Errorf("%w: remote_write_password requires remote_write_username", ErrInvalidFieldValue))
+	}
+
+	for _, label := range c.RemoteWriteExternalLabels {
+		name, _, ok := strings.Cut(label, "=")
+		if !ok || !validLabelName(name) {
+			errs = append(errs, fmt.Errorf(
+				"%w: invalid remote_write_external_labels entry '%s' (must be 'name=value' with a Prometheus label name)",
+				ErrInvalidFieldValue,
+				label,
+			))
+		}
+	}
+
+	return errs
+}
+
+// validLabelName reports whether the name is a Prometheus label name that is not reserved for internal use.
+func validLabelName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, char := range name {
+		letter := char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+		if !letter && (i == 0 || char < '0' || char > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSemantics checks that the URLs and DNs can be parsed and the enabled collectors exist.
+func (c *ExporterConfig) validateSemantics() []error {
+	var errs []error
+
+	if c.LDAPServerURL != "" || len(c.LDAPServerURLs) > 0 {
+		for _, serverURL := range c.ServerURLs() {
+			err := validateServerURL(serverURL)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%w: invalid LDAP server URL '%s': %w", ErrInvalidFieldValue, serverURL, err))
+			}
+		}
+	}
+
+	if c.LDAPBindDN != "" {
+		_, err := ldap.ParseDN(c.LDAPBindDN)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: invalid ldap_bind_dn: %w", ErrInvalidFieldValue, err))
+		}
+	}
+
+	for _, entry := range c.DSNumSubordinateRecords {
+		_, err := ldap.ParseDN(entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"%w: invalid ds_numsubordinate_records entry '%s': %w",
+				ErrInvalidFieldValue,
+				entry,
+				err,
+			))
+		}
+	}
+
+	if c.OTLPMetricsEndpoint != "" {
+		err := validatePushURL(c.OTLPMetricsEndpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: invalid otlp_metrics_endpoint: %w", ErrInvalidFieldValue, err))
+		}
+	}
+
+	for _, header := range c.OTLPMetricsHeaders {
+		name, _, ok := strings.Cut(header, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf(
+				"%w: invalid otlp_metrics_headers entry '%s' (must be 'name=value')",
+				ErrInvalidFieldValue,
+				header,
+			))
+		}
+	}
+
+	for _, name := range c.CollectorsEnabled {
+		if !knownCollector(name) {
+			errs = append(errs, fmt.Errorf(
+				"%w: unknown collector in collectors_enabled: %s (must be one of: %s)",
+				ErrInvalidFieldValue,
+				name,
+				strings.Join(CollectorNames(), ", "),
+			))
+		}
+	}
+
+	return errs
+}
+
+// validateServerURL checks that the LDAP server URL has an LDAP scheme and a host.
+func validateServerURL(serverURL string) error {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+
+	switch parsed.Scheme {
+	case "ldap", "ldaps":
+		if parsed.Host == "" {
+			return errors.New("host is not specified")
+		}
+	case "ldapi":
+	default:
+		return fmt.Errorf("unsupported scheme '%s' (must be 'ldap', 'ldaps' or 'ldapi')", parsed.Scheme)
+	}
+	return nil
+}
+
+// validatePushURL checks that the URL the metrics are pushed to has an HTTP scheme and a host.
+func validatePushURL(pushURL string) error {
+	parsed, err := url.Parse(pushURL)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme '%s' (must be 'http' or 'https')", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return errors.New("host is not specified")
+	}
 	return nil
 }
 
-// ReadConfig reads the configuration and returns it as a structure.
+// CollectorNames returns the names of the collectors that can be enabled.
+// The instanced collectors can also be enabled for a single instance,
+// e.g. ldbm-instance_userRoot or numsubordinates_ou=people,dc=example,dc=com.
+func CollectorNames() []string {
+	return []string{
+		"server",
+		"snmp-server",
+		"ndn-cache",
+		"ldbm-instance",
+		"numsubordinates",
+		"bdb-caches",
+		"bdb-internal",
+		"lmdb-internal",
+		"exporter-pool",
+		"rootdse",
+		"usn",
+	}
+}
+
+// InstancedCollectorNames returns the names of the collectors registered once per instance
+// with the "<name>_<instance>" name.
+func InstancedCollectorNames() []string {
+	return []string{"ldbm-instance", "numsubordinates"}
+}
+
+// CollectorFamily returns the name of the collector without the instance suffix.
+func CollectorFamily(name string) string {
+	family, _, ok := strings.Cut(name, "_")
+	if ok && slices.Contains(InstancedCollectorNames(), family) {
+		return family
+	}
+	return name
+}
+
+// knownCollector reports whether the collector name refers to an existing collector.
+func knownCollector(name string) bool {
+	return slices.Contains(CollectorNames(), CollectorFamily(name))
+}
+
+// ServerURLs returns the LDAP server URLs to connect to.
+func (c *ExporterConfig) ServerURLs() []string {
+	if len(c.LDAPServerURLs) > 0 {
+		return c.LDAPServerURLs
+	}
+	return []string{c.LDAPServerURL}
+}
+
+// ReadConfig reads the configuration file, overrides its values with the environment variables
+// and returns it as a structure.
 func ReadConfig(filename string) (*ExporterConfig, error) {
 	// #nosec G304: path comes from trusted config
 	data, err := os.ReadFile(filename)
@@ -173,12 +615,41 @@ func ReadConfig(filename string) (*ExporterConfig, error) {
 	}
 
 	var raw rawConfig
-	err = yaml.Unmarshal(data, &raw)
+	err = yaml.UnmarshalStrict(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.withEnv()
+}
+
+// ReadEnvConfig returns the configuration built from the environment variables only.
+func ReadEnvConfig() (*ExporterConfig, error) {
+	var raw rawConfig
+	return raw.withEnv()
+}
+
+// withEnv applies the environment variables and converts the raw configuration.
+func (r *rawConfig) withEnv() (*ExporterConfig, error) {
+	sources := r.fileSources()
+	err := r.applyEnv(sources)
 	if err != nil {
 		return nil, err
 	}
 
-	return raw.toConfig(), nil
+	cfg := r.toConfig()
+	cfg.sources = sources
+	return cfg, nil
+}
+
+// Source returns the source of the configuration value with the YAML key:
+// SourceEnv, SourceFile or SourceDefault.
+func (c *ExporterConfig) Source(key string) string {
+	source, ok := c.sources[key]
+	if !ok {
+		return SourceDefault
+	}
+	return source
 }
 
 // String returns the configuration as a string containing the yaml document.
@@ -187,6 +658,13 @@ func (c *ExporterConfig) String() string {
 	if safeCfg.LDAPBindPw != "" {
 		safeCfg.LDAPBindPw = "*****"
 	}
+	safeCfg.OTLPMetricsHeaders = maskHeaders(safeCfg.OTLPMetricsHeaders)
+	if safeCfg.RemoteWritePassword != "" {
+		safeCfg.RemoteWritePassword = "*****"
+	}
+	if safeCfg.RemoteWriteBearerToken != "" {
+		safeCfg.RemoteWriteBearerToken = "*****"
+	}
 
 	out, err := yaml.Marshal(&safeCfg)
 	if err != nil {
@@ -194,3 +672,32 @@ func (c *ExporterConfig) String() string {
 	}
 	return string(out)
 }
+
+// maskHeaders hides the values of the "name=value" headers, which usually carry credentials.
+func maskHeaders(headers []string) []string {
+	if headers == nil {
+		return nil
+	}
+
+	masked := make([]string, 0, len(headers))
+	for _, header := range headers {
+		name, _, _ := strings.Cut(header, "=")
+		masked = append(masked, name+"=*****")
+	}
+	return masked
+}
+
+// StringWithSources returns the configuration as a yaml document with the source of every value in a comment.
+func (c *ExporterConfig) StringWithSources() string {
+	var builder strings.Builder
+	for line := range strings.Lines(c.String()) {
+		key, _, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-") {
+			builder.WriteString(line)
+			continue
+		}
+		builder.WriteString(strings.TrimSuffix(line, "\n"))
+		builder.WriteString(" # " + c.Source(key) + "\n")
+	}
+	return builder.String()
+}
@@ -21,10 +21,15 @@ func TestLoadValidConfig(t *testing.T) {
 
 	require.Equal(t, config.CollectorsDefault, "none")
 	require.Equal(t, config.CollectorsEnabled, []string{"server", "snmp-server"})
+	require.Equal(t, config.CollectorsMinInterval, 10)
+	require.Equal(t, config.CollectorsBackgroundInterval, 30)
+	require.Equal(t, config.CollectorsStaleWindow, 600)
 	require.Equal(t, config.DSBackendType, "mdb")
 	require.Equal(t, config.DSBackendDBs, []string{"userRoot"})
+	require.Equal(t, config.DSDiscoveryInterval, 600)
 	require.Equal(t, config.DSNumSubordinateRecords, []string{"cn=users,cn=accounts,dc=example,dc=com"})
 	require.Equal(t, config.ShutdownTimeout, 5)
+	require.Equal(t, config.ScrapeTimeoutOffset, 1.5)
 	require.Equal(t, config.LDAPServerURL, "ldap://localhost:389")
 	require.Equal(t, config.LDAPBindDN, "cn=directory manager")
 	require.Equal(t, config.LDAPBindPw, "12345678")
@@ -34,6 +39,10 @@ func TestLoadValidConfig(t *testing.T) {
 	require.Equal(t, config.LDAPDialTimeout, 3)
 	require.Equal(t, config.LDAPPoolIdleTime, 600)
 	require.Equal(t, config.LDAPPoolLifeTime, 3600)
+	require.Equal(t, config.LDAPPoolMinIdle, 2)
+	require.Equal(t, config.LDAPPoolIdleCheckInterval, 30)
+	require.Equal(t, config.LDAPCircuitInitialBackoff, 0.5)
+	require.Equal(t, config.LDAPCircuitMaxBackoff, 120.0)
 }
 
 func TestDefaultConfigValues(t *testing.T) {
@@ -43,11 +52,20 @@ func TestDefaultConfigValues(t *testing.T) {
 
 	require.Equal(t, config.CollectorsDefault, defaultCollectorsDefault)
 	require.Equal(t, config.CollectorsEnabled, []string(nil))
+	require.Equal(t, config.CollectorsMinInterval, defaultCollectorsMinInterval)
+	require.Equal(t, config.CollectorsBackgroundInterval, defaultCollectorsBackgroundInterval)
+	require.Equal(t, config.CollectorsStaleWindow, defaultCollectorsStaleWindow)
 	require.Equal(t, config.DSBackendType, "")
 	require.Equal(t, config.DSBackendDBs, []string(nil))
+	require.Equal(t, config.DSDiscoveryInterval, defaultDSDiscoveryInterval)
 	require.Equal(t, config.DSNumSubordinateRecords, []string(nil))
 	require.Equal(t, config.ShutdownTimeout, defaultShutdownTimeout)
+	require.Equal(t, config.ScrapeTimeoutOffset, defaultScrapeTimeoutOffset)
 	require.Equal(t, config.LDAPServerURL, "ldap://localhost:389")
+	require.Equal(t, config.LDAPServerURLs, []string(nil))
+	require.Equal(t, config.ServerURLs(), []string{"ldap://localhost:389"})
+	require.Equal(t, config.LDAPFailoverStrategy, defaultLDAPFailoverStrategy)
+	require.Equal(t, config.LDAPEndpointDemotionTime, defaultLDAPEndpointDemotionTime)
 	require.Equal(t, config.LDAPBindDN, "cn=directory manager")
 	require.Equal(t, config.LDAPBindPw, "12345678")
 	require.Equal(t, config.LDAPTlsSkipVerify, defaultLDAPTlsSkipVerify)
@@ -56,6 +74,28 @@ func TestDefaultConfigValues(t *testing.T) {
 	require.Equal(t, config.LDAPDialTimeout, defaultLDAPDialTimeout)
 	require.Equal(t, config.LDAPPoolIdleTime, defaultLDAPPoolIdleTime)
 	require.Equal(t, config.LDAPPoolLifeTime, defaultLDAPPoolLifeTime)
+	require.Equal(t, config.LDAPPoolMinIdle, defaultLDAPPoolMinIdle)
+	require.Equal(t, config.LDAPPoolIdleCheckInterval, defaultLDAPPoolIdleCheckInterval)
+	require.Equal(t, config.LDAPCircuitInitialBackoff, defaultLDAPCircuitInitialBackoff)
+	require.Equal(t, config.LDAPCircuitMaxBackoff, defaultLDAPCircuitMaxBackoff)
+	require.Equal(t, config.OTLPMetricsEndpoint, "")
+	require.Equal(t, config.OTLPMetricsProtocol, defaultOTLPMetricsProtocol)
+	require.Equal(t, config.OTLPMetricsInterval, defaultOTLPMetricsInterval)
+	require.Equal(t, config.OTLPMetricsHeaders, []string(nil))
+	require.Equal(t, config.RemoteWriteURL, "")
+	require.Equal(t, config.RemoteWriteInterval, defaultRemoteWriteInterval)
+	require.Equal(t, config.RemoteWriteQueueSize, defaultRemoteWriteQueueSize)
+	require.Equal(t, config.RemoteWriteExternalLabels, []string(nil))
+}
+
+func TestFailoverConfig(t *testing.T) {
+	config := getConf(t, "testdata/failover.yml")
+	err := config.Validate()
+	require.NoError(t, err, "Validating a config with several LDAP URLs should not result in an error")
+
+	require.Equal(t, config.ServerURLs(), []string{"ldap://ds1.example.com:389", "ldap://ds2.example.com:389"})
+	require.Equal(t, config.LDAPFailoverStrategy, FailoverRoundRobin)
+	require.Equal(t, config.LDAPEndpointDemotionTime, 120)
 }
 
 func TestNoRequiredConfigValues(t *testing.T) {
@@ -78,6 +118,15 @@ func TestNoRequiredConfigValues(t *testing.T) {
 func TestConfigPrinting(t *testing.T) {
 	config := getConf(t, "testdata/valid.yml")
 	require.Contains(t, config.String(), "ldap_bind_pw: '*****'")
+
+	config.OTLPMetricsHeaders = []string{"Authorization=Bearer secret"}
+	require.Contains(t, config.String(), "Authorization=*****")
+	require.NotContains(t, config.String(), "secret")
+
+	config.RemoteWritePassword = "secret"
+	config.RemoteWriteBearerToken = "secret"
+	require.Contains(t, config.String(), "remote_write_password: '*****'")
+	require.NotContains(t, config.String(), "secret")
 }
 
 func TestConfigFileReadErrors(t *testing.T) {
@@ -96,6 +145,26 @@ func TestFieldValuesValidation(t *testing.T) {
 	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
 	require.ErrorContains(t, err, "shutdown_timeout")
 
+	config = getConf(t, "testdata/invalid-collectors-min-interval.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "collectors_min_interval")
+
+	config = getConf(t, "testdata/invalid-collectors-default.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "invalid collectors_default:")
+
+	config = getConf(t, "testdata/invalid-collectors-background-interval.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "collectors_background_interval")
+
+	config = getConf(t, "testdata/invalid-ds-discovery-interval.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "ds_discovery_interval")
+
 	config = getConf(t, "testdata/invalid-ldap-limit.yml")
 	err = config.Validate()
 	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
@@ -106,13 +175,74 @@ func TestFieldValuesValidation(t *testing.T) {
 	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
 	require.ErrorContains(t, err, "ldap_pool_get_timeout")
 
+	config = getConf(t, "testdata/invalid-ldap-min-idle.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "ldap_pool_min_idle")
+
 	config = getConf(t, "testdata/invalid-ldap-dial-timeout.yml")
 	err = config.Validate()
 	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
 	require.ErrorContains(t, err, "ldap_dial_timeout")
 
+	config = getConf(t, "testdata/invalid-failover-strategy.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "invalid ldap_failover_strategy:")
+
+	config = getConf(t, "testdata/invalid-ldap-server-urls.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "ldap_server_urls")
+
+	config = getConf(t, "testdata/invalid-ldap-circuit-backoff.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "ldap_circuit_max_backoff")
+
 	config = getConf(t, "testdata/invalid-backend-type.yml")
 	err = config.Validate()
 	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
 	require.ErrorContains(t, err, "invalid ds_backend_type:")
+
+	config = getConf(t, "testdata/invalid-otlp-metrics.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "invalid otlp_metrics_endpoint:")
+	require.ErrorContains(t, err, "invalid otlp_metrics_protocol:")
+	require.ErrorContains(t, err, "invalid otlp_metrics_interval:")
+	require.ErrorContains(t, err, "invalid otlp_metrics_headers entry 'Authorization'")
+
+	config = getConf(t, "testdata/invalid-remote-write.yml")
+	err = config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue, "Validation configuration with invalid field should fail")
+	require.ErrorContains(t, err, "invalid remote_write_url:")
+	require.ErrorContains(t, err, "invalid remote_write_interval:")
+	require.ErrorContains(t, err, "invalid remote_write_queue_size:")
+	require.ErrorContains(t, err, "remote_write_bearer_token and remote_write_username cannot be specified together")
+	require.ErrorContains(t, err, "invalid remote_write_external_labels entry '__name__=site'")
+	require.ErrorContains(t, err, "invalid remote_write_external_labels entry '1site=branch'")
+}
+
+func TestUnknownConfigKey(t *testing.T) {
+	config, err := ReadConfig("testdata/unknown-key.yml")
+	require.Error(t, err, "Unknown keys should be rejected")
+	require.ErrorContains(t, err, "line 5")
+	require.ErrorContains(t, err, "ldap_pool_con_limit")
+	require.Nil(t, config)
+}
+
+func TestAllValidationErrorsReported(t *testing.T) {
+	config := getConf(t, "testdata/invalid-semantics.yml")
+	err := config.Validate()
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+
+	require.ErrorContains(t, err, "ldap_pool_conn_limit")
+	require.ErrorContains(t, err, "invalid LDAP server URL 'http://ds2.example.com'")
+	require.ErrorContains(t, err, "invalid ldap_bind_dn")
+	require.ErrorContains(t, err, "invalid ds_numsubordinate_records entry 'not a dn'")
+	require.ErrorContains(t, err, "unknown collector in collectors_enabled: sever")
+	require.NotContains(t, err.Error(), "collectors_enabled: ldbm-instance_userRoot",
+		"Instanced collector names should be accepted")
+	require.NotContains(t, err.Error(), "ds1.example.com")
 }
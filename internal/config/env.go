@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the configuration fields.
+// The variable name is the prefix followed by the upper-cased YAML key, e.g. DS_EXPORTER_LDAP_SERVER_URL.
+const EnvPrefix = "DS_EXPORTER_"
+
+// Sources of the configuration values.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// EnvName returns the name of the environment variable overriding the configuration field with the YAML key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// yamlKey returns the YAML key of the struct field.
+func yamlKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return key
+}
+
+// fileSources returns the source of every field: SourceFile for the fields set in the file, otherwise SourceDefault.
+func (r *rawConfig) fileSources() map[string]string {
+	sources := make(map[string]string)
+
+	value := reflect.ValueOf(r).Elem()
+	for i := range value.NumField() {
+		key := yamlKey(value.Type().Field(i))
+		if value.Field(i).IsNil() {
+			sources[key] = SourceDefault
+		} else {
+			sources[key] = SourceFile
+		}
+	}
+	return sources
+}
+
+// applyEnv overrides the fields with the values of the set environment variables and marks them in sources.
+// Empty variables are treated as unset, except for the lists, which they clear.
+func (r *rawConfig) applyEnv(sources map[string]string) error {
+	value := reflect.ValueOf(r).Elem()
+	for i := range value.NumField() {
+		key := yamlKey(value.Type().Field(i))
+		envValue, ok := os.LookupEnv(EnvName(key))
+		if !ok || (envValue == "" && value.Field(i).Kind() != reflect.Slice) {
+			continue
+		}
+
+		err := setFromEnv(value.Field(i), envValue)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidFieldValue, EnvName(key), err)
+		}
+		sources[key] = SourceEnv
+	}
+	return nil
+}
+
+// setFromEnv parses the environment variable value into the rawConfig field, which is a pointer or a string slice.
+func setFromEnv(field reflect.Value, envValue string) error {
+	if field.Kind() == reflect.Slice {
+		list, err := parseEnvList(envValue)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+
+	parsed := reflect.New(field.Type().Elem())
+	switch parsed.Elem().Kind() {
+	case reflect.String:
+		parsed.Elem().SetString(envValue)
+	case reflect.Int:
+		number, err := strconv.Atoi(envValue)
+		if err != nil {
+			return err
+		}
+		parsed.Elem().SetInt(int64(number))
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(envValue, 64)
+		if err != nil {
+			return err
+		}
+		parsed.Elem().SetFloat(number)
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(envValue)
+		if err != nil {
+			return err
+		}
+		parsed.Elem().SetBool(flag)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	field.Set(parsed)
+	return nil
+}
+
+// parseEnvList parses a list value: a YAML sequence, e.g. [server, ndn-cache] or ["ou=people,dc=example,dc=com"],
+// or any other value as a single item, so that DNs and header values may contain commas.
+// An empty value is an empty list.
+func parseEnvList(envValue string) ([]string, error) {
+	envValue = strings.TrimSpace(envValue)
+	if envValue == "" {
+		return []string{}, nil
+	}
+
+	var list []string
+	err := yaml.Unmarshal([]byte(envValue), &list)
+	if err == nil {
+		return list, nil
+	}
+	if strings.HasPrefix(envValue, "[") || strings.HasPrefix(envValue, "- ") {
+		return nil, fmt.Errorf("invalid YAML sequence: %w", err)
+	}
+	return []string{envValue}, nil
+}
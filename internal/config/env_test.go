@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvOverridesFile(t *testing.T) {
+	t.Setenv("DS_EXPORTER_LDAP_SERVER_URL", "ldaps://ds.example.com:636")
+	t.Setenv("DS_EXPORTER_COLLECTORS_ENABLED", "[server, ndn-cache]")
+	t.Setenv("DS_EXPORTER_LDAP_POOL_CONN_LIMIT", "8")
+	t.Setenv("DS_EXPORTER_LDAP_CIRCUIT_MAX_BACKOFF", "30.5")
+	t.Setenv("DS_EXPORTER_LDAP_TLS_SKIP_VERIFY", "true")
+	t.Setenv("DS_EXPORTER_LDAP_BIND_DN", "")
+
+	config := getConf(t, "testdata/valid.yml")
+	require.NoError(t, config.Validate())
+
+	require.Equal(t, "ldaps://ds.example.com:636", config.LDAPServerURL)
+	require.Equal(t, []string{"server", "ndn-cache"}, config.CollectorsEnabled)
+	require.Equal(t, 8, config.LDAPPoolConnLimit)
+	require.Equal(t, 30.5, config.LDAPCircuitMaxBackoff)
+	require.True(t, config.LDAPTlsSkipVerify)
+	require.Equal(t, "cn=directory manager", config.LDAPBindDN, "Empty variables should be ignored")
+
+	require.Equal(t, SourceEnv, config.Source("ldap_server_url"))
+	require.Equal(t, SourceEnv, config.Source("collectors_enabled"))
+	require.Equal(t, SourceFile, config.Source("ldap_bind_dn"))
+	require.Equal(t, SourceDefault, config.Source("ldap_failover_strategy"))
+
+	printed := config.StringWithSources()
+	require.Contains(t, printed, "ldap_server_url: ldaps://ds.example.com:636 # env\n")
+	require.Contains(t, printed, "ldap_bind_pw: '*****' # file\n")
+	require.Contains(t, printed, "ldap_failover_strategy: ordered # default\n")
+}
+
+func TestEnvOnlyConfig(t *testing.T) {
+	t.Setenv("DS_EXPORTER_LDAP_SERVER_URLS", "[ldap://ds1:389, ldap://ds2:389]")
+	t.Setenv("DS_EXPORTER_LDAP_BIND_DN", "cn=directory manager")
+	t.Setenv("DS_EXPORTER_LDAP_BIND_PW", "secret")
+
+	config, err := ReadEnvConfig()
+	require.NoError(t, err)
+	require.NoError(t, config.Validate(), "Required values from the environment should be enough")
+	require.Equal(t, []string{"ldap://ds1:389", "ldap://ds2:389"}, config.ServerURLs())
+	require.Equal(t, defaultLDAPPoolConnLimit, config.LDAPPoolConnLimit)
+}
+
+func TestEnvLists(t *testing.T) {
+	t.Setenv("DS_EXPORTER_DS_NUMSUBORDINATE_RECORDS", "ou=people,dc=example,dc=com")
+	t.Setenv("DS_EXPORTER_COLLECTORS_ENABLED", `["server", "numsubordinates_ou=groups,dc=example,dc=com"]`)
+	t.Setenv("DS_EXPORTER_REMOTE_WRITE_EXTERNAL_LABELS", "")
+
+	config := getConf(t, "testdata/valid.yml")
+	require.Equal(t, []string{"ou=people,dc=example,dc=com"}, config.DSNumSubordinateRecords,
+		"A value that is not a sequence should be a single DN")
+	require.Equal(t, []string{"server", "numsubordinates_ou=groups,dc=example,dc=com"}, config.CollectorsEnabled)
+	require.Empty(t, config.RemoteWriteExternalLabels, "An empty variable should clear the list")
+	require.Equal(t, SourceEnv, config.Source("remote_write_external_labels"))
+}
+
+func TestInvalidEnvList(t *testing.T) {
+	t.Setenv("DS_EXPORTER_COLLECTORS_ENABLED", "[server, ndn-cache")
+
+	_, err := ReadEnvConfig()
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+	require.ErrorContains(t, err, "DS_EXPORTER_COLLECTORS_ENABLED")
+}
+
+func TestInvalidEnvValue(t *testing.T) {
+	t.Setenv("DS_EXPORTER_LDAP_POOL_CONN_LIMIT", "many")
+
+	_, err := ReadEnvConfig()
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+	require.ErrorContains(t, err, "DS_EXPORTER_LDAP_POOL_CONN_LIMIT")
+}
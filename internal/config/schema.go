@@ -0,0 +1,301 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNoSchemaEntry indicates that a configuration field has no entry in the JSON Schema description table.
+var ErrNoSchemaEntry = errors.New("configuration field has no schema entry")
+
+// jsonSchema is a subset of the JSON Schema (draft-07) keywords used to describe the configuration.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// schemaEntry describes a configuration field in the JSON Schema.
+type schemaEntry struct {
+	description  string
+	defaultValue any      // nil if the field has no default value
+	enum         []string // allowed values of the field or of the list items
+	pattern      string   // pattern of the list items allowed in addition to enum
+	minimum      *float64
+}
+
+// minValue returns a pointer to the minimum value of a numeric field.
+func minValue(value float64) *float64 {
+	return &value
+}
+
+// schemaEntries returns the JSON Schema descriptions of the configuration fields by YAML key.
+func schemaEntries() map[string]schemaEntry {
+	return map[string]schemaEntry{
+		"shutdown_timeout": {
+			description:  "Time in seconds given to the exporter to shut down gracefully.",
+			defaultValue: defaultShutdownTimeout,
+			minimum:      minValue(0),
+		},
+		"scrape_timeout_offset": {
+			description:  "Time in seconds subtracted from the Prometheus scrape timeout to answer before it expires.",
+			defaultValue: defaultScrapeTimeoutOffset,
+			minimum:      minValue(0),
+		},
+		"collectors_default": {
+			description:  "Set of collectors enabled by default.",
+			defaultValue: defaultCollectorsDefault,
+			enum:         []string{"all", "none", "standard"},
+		},
+		"collectors_enabled": {
+			description: "List of explicitly enabled collectors. " +
+				"Instanced collectors can be enabled for a single instance with the <name>_<instance> name.",
+			enum:    CollectorNames(),
+			pattern: "^(" + strings.Join(InstancedCollectorNames(), "|") + ")_.+$",
+		},
+		"collectors_min_interval": {
+			description:  "Minimum interval in seconds between two LDAP scrapes of the same collector. 0 disables caching.",
+			defaultValue: defaultCollectorsMinInterval,
+			minimum:      minValue(0),
+		},
+		"collectors_background_interval": {
+			description:  "Interval in seconds of the background collection. 0 disables the background mode.",
+			defaultValue: defaultCollectorsBackgroundInterval,
+			minimum:      minValue(0),
+		},
+		"collectors_stale_window": {
+			description:  "Time in seconds a failing collector keeps its last successful series in the background mode.",
+			defaultValue: defaultCollectorsStaleWindow,
+			minimum:      minValue(0),
+		},
+		"collectors_subtree_search": {
+			description:  "Read all monitoring entries with subtree searches instead of one search per collector.",
+			defaultValue: defaultCollectorsSubtreeSearch,
+		},
+		"ds_numsubordinate_records": {
+			description: "DNs of the entries for which the number of subordinate entries is collected.",
+		},
+		"ds_backend_type": {
+			description:  "Backend database type. Detected automatically if empty.",
+			defaultValue: "",
+			enum:         []string{BackendBDB, BackendMDB, ""},
+		},
+		"ds_backend_dbs": {
+			description: "Backend database instances. Detected automatically if empty.",
+		},
+		"ds_discovery_interval": {
+			description:  "Interval in seconds of the backend type and instances re-discovery. 0 disables it.",
+			defaultValue: defaultDSDiscoveryInterval,
+			minimum:      minValue(0),
+		},
+		"ldap_server_url": {
+			description: "LDAP server URL. Cannot be used together with ldap_server_urls.",
+		},
+		"ldap_server_urls": {
+			description: "LDAP server URLs to fail over between. Cannot be used together with ldap_server_url.",
+		},
+		"ldap_failover_strategy": {
+			description:  "Order in which the LDAP server URLs are tried.",
+			defaultValue: defaultLDAPFailoverStrategy,
+			enum:         []string{FailoverOrdered, FailoverRoundRobin},
+		},
+		"ldap_endpoint_demotion_time": {
+			description:  "Time in seconds a failed LDAP server URL is tried only after the healthy ones.",
+			defaultValue: defaultLDAPEndpointDemotionTime,
+			minimum:      minValue(0),
+		},
+		"ldap_bind_dn": {
+			description: "DN used to bind to the LDAP server.",
+		},
+		"ldap_bind_pw": {
+			description: "Password used to bind to the LDAP server.",
+		},
+		"ldap_tls_skip_verify": {
+			description:  "Skip the verification of the LDAP server certificate.",
+			defaultValue: defaultLDAPTlsSkipVerify,
+		},
+		"ldap_pool_conn_limit": {
+			description:  "Maximum size of the LDAP connection pool.",
+			defaultValue: defaultLDAPPoolConnLimit,
+			minimum:      minValue(1),
+		},
+		"ldap_pool_get_timeout": {
+			description:  "Timeout in seconds for obtaining a connection from the pool.",
+			defaultValue: defaultLDAPPoolGetTimeout,
+			minimum:      minValue(1),
+		},
+		"ldap_pool_idle_time": {
+			description:  "Time in seconds after which an idle connection is closed.",
+			defaultValue: defaultLDAPPoolIdleTime,
+		},
+		"ldap_pool_life_time": {
+			description:  "Maximum lifetime of a connection in seconds.",
+			defaultValue: defaultLDAPPoolLifeTime,
+		},
+		"ldap_pool_min_idle": {
+			description:  "Minimum number of idle connections kept in the pool.",
+			defaultValue: defaultLDAPPoolMinIdle,
+			minimum:      minValue(0),
+		},
+		"ldap_pool_idle_check_interval": {
+			description:  "Interval in seconds of the idle connections liveness check. 0 disables it.",
+			defaultValue: defaultLDAPPoolIdleCheckInterval,
+			minimum:      minValue(0),
+		},
+		"ldap_dial_timeout": {
+			description:  "Timeout in seconds for establishing a connection to the LDAP server.",
+			defaultValue: defaultLDAPDialTimeout,
+			minimum:      minValue(1),
+		},
+		"ldap_circuit_initial_backoff": {
+			description:  "Time in seconds new connections fail fast after the first failed dial. 0 disables the breaker.",
+			defaultValue: defaultLDAPCircuitInitialBackoff,
+			minimum:      minValue(0),
+		},
+		"ldap_circuit_max_backoff": {
+			description:  "Maximum time in seconds new connections fail fast after consecutive failed dials.",
+			defaultValue: defaultLDAPCircuitMaxBackoff,
+			minimum:      minValue(0),
+		},
+		"otlp_metrics_endpoint": {
+			description: "URL of the OpenTelemetry collector the metrics are pushed to. Empty disables the OTLP push.",
+		},
+		"otlp_metrics_protocol": {
+			description:  "Protocol used to push the metrics to otlp_metrics_endpoint.",
+			defaultValue: defaultOTLPMetricsProtocol,
+			enum:         []string{OTLPProtocolHTTP, OTLPProtocolGRPC},
+		},
+		"otlp_metrics_interval": {
+			description:  "Interval in seconds between two OTLP metric pushes.",
+			defaultValue: defaultOTLPMetricsInterval,
+			minimum:      minValue(1),
+		},
+		"otlp_metrics_headers": {
+			description: "Headers sent with every OTLP metric push, in the name=value form.",
+		},
+		"remote_write_url": {
+			description: "Prometheus remote-write URL the metrics are sent to. Empty disables the remote write.",
+		},
+		"remote_write_interval": {
+			description:  "Interval in seconds between two remote-write requests.",
+			defaultValue: defaultRemoteWriteInterval,
+			minimum:      minValue(1),
+		},
+		"remote_write_queue_size": {
+			description:  "Maximum number of gathered batches kept in memory while the remote-write endpoint fails.",
+			defaultValue: defaultRemoteWriteQueueSize,
+			minimum:      minValue(1),
+		},
+		"remote_write_username": {
+			description: "Username of the basic authentication of the remote-write endpoint.",
+		},
+		"remote_write_password": {
+			description: "Password of the basic authentication of the remote-write endpoint.",
+		},
+		"remote_write_bearer_token": {
+			description: "Bearer token sent to the remote-write endpoint. Cannot be used together with basic authentication.",
+		},
+		"remote_write_external_labels": {
+			description: "Labels added to every remote-written series, in the name=value form.",
+		},
+	}
+}
+
+// configFieldKeys returns the YAML keys and types of the ExporterConfig fields.
+func configFieldKeys() map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	configType := reflect.TypeFor[ExporterConfig]()
+	for i := range configType.NumField() {
+		field := configType.Field(i)
+		key := yamlKey(field)
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		fields[key] = field.Type
+	}
+	return fields
+}
+
+// Schema returns the JSON Schema of the configuration file.
+func Schema() ([]byte, error) {
+	entries := schemaEntries()
+	additionalProperties := false
+
+	schema := &jsonSchema{
+		Schema:               "http://json-schema.org/draft-07/schema#",
+		Title:                "389-ds-exporter configuration",
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: &additionalProperties,
+	}
+
+	for key, fieldType := range configFieldKeys() {
+		entry, ok := entries[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoSchemaEntry, key)
+		}
+
+		property, err := fieldSchema(fieldType, entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		schema.Properties[key] = property
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(schema)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fieldSchema returns the JSON Schema of a configuration field.
+func fieldSchema(fieldType reflect.Type, entry schemaEntry) (*jsonSchema, error) {
+	property := &jsonSchema{
+		Description: entry.description,
+		Default:     entry.defaultValue,
+		Minimum:     entry.minimum,
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		property.Type = "string"
+		property.Enum = entry.enum
+	case reflect.Int:
+		property.Type = "integer"
+	case reflect.Float64:
+		property.Type = "number"
+	case reflect.Bool:
+		property.Type = "boolean"
+	case reflect.Slice:
+		property.Type = "array"
+		property.Items = &jsonSchema{Type: "string", Enum: entry.enum}
+		if entry.pattern != "" {
+			property.Items = &jsonSchema{
+				Type:  "string",
+				AnyOf: []*jsonSchema{{Enum: entry.enum}, {Pattern: entry.pattern}},
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", fieldType)
+	}
+	return property, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaCoversAllFields(t *testing.T) {
+	entries := schemaEntries()
+	for key := range configFieldKeys() {
+		entry, ok := entries[key]
+		require.True(t, ok, "Configuration field %s has no schema entry", key)
+		require.NotEmpty(t, entry.description, "Configuration field %s has no schema description", key)
+	}
+	for key := range entries {
+		_, ok := configFieldKeys()[key]
+		require.True(t, ok, "Schema entry %s does not correspond to a configuration field", key)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+	require.Equal(t, false, schema["additionalProperties"])
+
+	properties := schema["properties"].(map[string]any)
+	require.Len(t, properties, len(configFieldKeys()))
+
+	poolLimit := properties["ldap_pool_conn_limit"].(map[string]any)
+	require.Equal(t, "integer", poolLimit["type"])
+	require.InDelta(t, defaultLDAPPoolConnLimit, poolLimit["default"], 0)
+
+	backendType := properties["ds_backend_type"].(map[string]any)
+	require.Equal(t, []any{"bdb", "mdb", ""}, backendType["enum"])
+
+	subtreeSearch := properties["collectors_subtree_search"].(map[string]any)
+	require.Equal(t, "boolean", subtreeSearch["type"])
+	require.Equal(t, false, subtreeSearch["default"], "False defaults should not be omitted")
+
+	enabled := properties["collectors_enabled"].(map[string]any)
+	require.Equal(t, "array", enabled["type"])
+}
@@ -0,0 +1,267 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"389-ds-exporter/internal/collectors"
+)
+
+// JSONHttpHandler returns the handler rendering the metrics as JSON: an array with an object per metric family
+// holding the name, help, type and the metrics of the family with their labels.
+// It selects, binds and traces the collectors like MetricsHttpHandler.
+func JSONHttpHandler(
+	registry prometheus.Gatherer,
+	dsCollector *collectors.DSCollector,
+	timeoutOffset time.Duration,
+) http.Handler {
+	return scrapeHandler(registry, dsCollector, timeoutOffset,
+		func(w http.ResponseWriter, _ *http.Request, gatherer prometheus.Gatherer) {
+			families, ok := gather(w, gatherer)
+			if !ok {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			err := json.NewEncoder(w).Encode(jsonFamilies(families))
+			if err != nil {
+				slog.Warn("Error writing JSON metrics", "err", err)
+			}
+		})
+}
+
+// InfluxHttpHandler returns the handler rendering the metrics in the InfluxDB line protocol:
+// a line per metric with the family name as the measurement and the labels as the tags.
+// It selects, binds and traces the collectors like MetricsHttpHandler.
+func InfluxHttpHandler(
+	registry prometheus.Gatherer,
+	dsCollector *collectors.DSCollector,
+	timeoutOffset time.Duration,
+) http.Handler {
+	return scrapeHandler(registry, dsCollector, timeoutOffset,
+		func(w http.ResponseWriter, _ *http.Request, gatherer prometheus.Gatherer) {
+			families, ok := gather(w, gatherer)
+			if !ok {
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, err := w.Write([]byte(influxLines(families, time.Now())))
+			if err != nil {
+				slog.Warn("Error writing InfluxDB metrics", "err", err)
+			}
+		})
+}
+
+// gather gathers the metrics and answers with 500 on error, like promhttp does by default.
+func gather(w http.ResponseWriter, gatherer prometheus.Gatherer) ([]*dto.MetricFamily, bool) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		slog.Error("Error gathering metrics", "err", err)
+		http.Error(w, "An error has occurred while gathering metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return families, true
+}
+
+// jsonFloat is a float encoded as a JSON number, or as a string if it is NaN or infinite.
+type jsonFloat float64
+
+// MarshalJSON encodes the float as a number or as "NaN", "+Inf" or "-Inf".
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	value := float64(f)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return json.Marshal(formatFloat(value))
+	}
+	return json.Marshal(value)
+}
+
+// jsonFamily is the JSON object of a metric family.
+type jsonFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help"`
+	Type    string       `json:"type"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+// jsonMetric is the JSON object of a metric. Counters, gauges and untyped metrics have a value,
+// histograms have buckets by upper bound and summaries have quantiles, both with a count and a sum.
+type jsonMetric struct {
+	Labels      map[string]string    `json:"labels"`
+	TimestampMs int64                `json:"timestamp_ms,omitempty"`
+	Value       *jsonFloat           `json:"value,omitempty"`
+	Buckets     map[string]uint64    `json:"buckets,omitempty"`
+	Quantiles   map[string]jsonFloat `json:"quantiles,omitempty"`
+	Count       *uint64              `json:"count,omitempty"`
+	Sum         *jsonFloat           `json:"sum,omitempty"`
+}
+
+// jsonFamilies converts the gathered metric families to their JSON objects.
+func jsonFamilies(families []*dto.MetricFamily) []jsonFamily {
+	result := make([]jsonFamily, 0, len(families))
+	for _, family := range families {
+		converted := jsonFamily{
+			Name:    family.GetName(),
+			Help:    family.GetHelp(),
+			Type:    strings.ToLower(family.GetType().String()),
+			Metrics: make([]jsonMetric, 0, len(family.GetMetric())),
+		}
+		for _, metric := range family.GetMetric() {
+			converted.Metrics = append(converted.Metrics, jsonMetricOf(family.GetType(), metric))
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// jsonMetricOf converts a metric of the given type to its JSON object.
+func jsonMetricOf(metricType dto.MetricType, metric *dto.Metric) jsonMetric {
+	converted := jsonMetric{
+		Labels:      make(map[string]string, len(metric.GetLabel())),
+		TimestampMs: metric.GetTimestampMs(),
+	}
+	for _, label := range metric.GetLabel() {
+		converted.Labels[label.GetName()] = label.GetValue()
+	}
+
+	setValue := func(value float64) {
+		converted.Value = new(jsonFloat(value))
+	}
+	setCountSum := func(count uint64, sum float64) {
+		converted.Count = &count
+		converted.Sum = new(jsonFloat(sum))
+	}
+
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		setValue(metric.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		setValue(metric.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		setValue(metric.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		converted.Buckets = make(map[string]uint64, len(histogram.GetBucket())+1)
+		for _, bucket := range histogram.GetBucket() {
+			converted.Buckets[formatFloat(bucket.GetUpperBound())] = bucket.GetCumulativeCount()
+		}
+		converted.Buckets["+Inf"] = histogram.GetSampleCount()
+		setCountSum(histogram.GetSampleCount(), histogram.GetSampleSum())
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		converted.Quantiles = make(map[string]jsonFloat, len(summary.GetQuantile()))
+		for _, quantile := range summary.GetQuantile() {
+			converted.Quantiles[formatFloat(quantile.GetQuantile())] = jsonFloat(quantile.GetValue())
+		}
+		setCountSum(summary.GetSampleCount(), summary.GetSampleSum())
+	}
+	return converted
+}
+
+// influxLines renders the metric families in the InfluxDB line protocol.
+// Counters, gauges and untyped metrics have a value field, histograms have a field per bucket upper bound
+// and summaries a field per quantile, both with the count and sum fields.
+// Metrics without a timestamp get now, NaN and infinite values are skipped since the protocol does not support them.
+func influxLines(families []*dto.MetricFamily, now time.Time) string {
+	var builder strings.Builder
+	for _, family := range families {
+		measurement := influxEscape(family.GetName(), ", ")
+		for _, metric := range family.GetMetric() {
+			var fields []string
+			addField := func(key string, value float64) {
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					return
+				}
+				fields = append(fields, influxEscape(key, ",= ")+"="+strconv.FormatFloat(value, 'g', -1, 64))
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				addField("value", metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				addField("value", metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				addField("value", metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.GetBucket() {
+					if !math.IsInf(bucket.GetUpperBound(), 1) {
+						addField(formatFloat(bucket.GetUpperBound()), float64(bucket.GetCumulativeCount()))
+					}
+				}
+				addField("+Inf", float64(histogram.GetSampleCount()))
+				addField("count", float64(histogram.GetSampleCount()))
+				addField("sum", histogram.GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					addField(formatFloat(quantile.GetQuantile()), quantile.GetValue())
+				}
+				addField("count", float64(summary.GetSampleCount()))
+				addField("sum", summary.GetSampleSum())
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			builder.WriteString(measurement)
+			for _, label := range metric.GetLabel() {
+				// Empty tag values are not allowed.
+				if label.GetValue() == "" {
+					continue
+				}
+				builder.WriteString(",")
+				builder.WriteString(influxEscape(label.GetName(), ",= ") + "=" + influxEscape(label.GetValue(), ",= "))
+			}
+			builder.WriteString(" " + strings.Join(fields, ","))
+
+			timestamp := now
+			if metric.TimestampMs != nil {
+				timestamp = time.UnixMilli(metric.GetTimestampMs())
+			}
+			builder.WriteString(" " + strconv.FormatInt(timestamp.UnixNano(), 10) + "\n")
+		}
+	}
+	return builder.String()
+}
+
+// influxEscape escapes the special characters of a measurement, tag or field key with a backslash.
+// Line breaks cannot be escaped in the protocol, so they are replaced by spaces, which are escaped if special.
+func influxEscape(value string, special string) string {
+	if !strings.ContainsAny(value, special+"\r\n") {
+		return value
+	}
+
+	var builder strings.Builder
+	for _, char := range value {
+		if char == '\r' || char == '\n' {
+			char = ' '
+		}
+		if strings.ContainsRune(special, char) {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+// formatFloat formats the bucket bound or quantile like the Prometheus text format does.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/collectors"
+)
+
+func newFormatsRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ds_ops_total",
+		Help: "Operations.",
+	}, []string{"backend", "empty"})
+	counter.WithLabelValues("user root,a=b", "").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "ds_ratio", Help: "Ratio."})
+	gauge.Set(math.NaN())
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ds_duration_seconds",
+		Help:    "Duration.",
+		Buckets: []float64{0.1, 1},
+	})
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	registry.MustRegister(counter, gauge, histogram)
+	return registry
+}
+
+func TestInfluxLines(t *testing.T) {
+	families, err := newFormatsRegistry(t).Gather()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	require.Equal(t,
+		"ds_duration_seconds 0.1=0,1=1,+Inf=2,count=2,sum=5.5 1700000000000000000\n"+
+			`ds_ops_total,backend=user\ root\,a\=b value=3 1700000000000000000`+"\n",
+		influxLines(families, now),
+		"Labels should become escaped tags, empty tags and NaN values should be skipped")
+}
+
+func TestInfluxLinesNewlines(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "ds_info", Help: "Info."}, []string{"version"})
+	gauge.WithLabelValues("389-Directory/3.1\nB2024").Set(1)
+	registry.MustRegister(gauge)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	require.Equal(t,
+		`ds_info,version=389-Directory/3.1\ B2024 value=1 1700000000000000000`+"\n",
+		influxLines(families, time.Unix(1700000000, 0)),
+		"Line breaks in tag values should not split the line")
+
+	require.Equal(t, `ds\ info`, influxEscape("ds\ninfo", ", "), "Line breaks in measurements should be replaced")
+	require.Equal(t, `a\ \ b`, influxEscape("a\r\nb", ",= "), "Carriage returns in keys should be replaced too")
+}
+
+func TestJSONFamilies(t *testing.T) {
+	families, err := newFormatsRegistry(t).Gather()
+	require.NoError(t, err)
+
+	data, err := json.Marshal(jsonFamilies(families))
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"name": "ds_duration_seconds", "help": "Duration.", "type": "histogram", "metrics": [
+			{"labels": {}, "buckets": {"0.1": 0, "1": 1, "+Inf": 2}, "count": 2, "sum": 5.5}
+		]},
+		{"name": "ds_ops_total", "help": "Operations.", "type": "counter", "metrics": [
+			{"labels": {"backend": "user root,a=b", "empty": ""}, "value": 3}
+		]},
+		{"name": "ds_ratio", "help": "Ratio.", "type": "gauge", "metrics": [
+			{"labels": {}, "value": "NaN"}
+		]}
+	]`, string(data))
+}
+
+func TestFormatHandlers(t *testing.T) {
+	ds := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	ds.Register("failing", failingCollector{})
+	registry := newFormatsRegistry(t)
+
+	for _, test := range []struct {
+		handler     http.Handler
+		contentType string
+	}{
+		{JSONHttpHandler(registry, ds, 0), "application/json"},
+		{InfluxHttpHandler(registry, ds, 0), "text/plain; charset=utf-8"},
+	} {
+		rec := httptest.NewRecorder()
+		test.handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics.format", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), "ds_ops_total")
+		require.Contains(t, rec.Body.String(), "ds_exporter_scrape_success", "Collector metrics should be rendered")
+
+		rec = httptest.NewRecorder()
+		test.handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics.format?collect[]=unknown", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, "Unknown collector should result in 400")
+	}
+}
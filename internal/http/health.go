@@ -9,76 +9,249 @@ import (
 
 	"github.com/go-ldap/ldap/v3"
 
+	"389-ds-exporter/internal/collectors"
 	expldap "389-ds-exporter/internal/ldap"
 )
 
-// HealthHttpResponse function performs exporter healcheck and returns its json result.
-func HealthHttpResponse(
+// BackendReporter reports the discovered backend type and instances.
+type BackendReporter interface {
+	Backend() (string, []string)
+}
+
+// collectorHealth is the health of a collector in the /health response.
+type collectorHealth struct {
+	LastScrape      string  `json:"last_scrape"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Success         bool    `json:"success"`
+	Error           string  `json:"error"`
+}
+
+// histogramHealth is a duration histogram of the pool in the /health response.
+type histogramHealth struct {
+	Count      uint64  `json:"count"`
+	SumSeconds float64 `json:"sum_seconds"`
+}
+
+// endpointHealth is the state of an LDAP server URL in the /health response.
+type endpointHealth struct {
+	URL     string `json:"url"`
+	Active  bool   `json:"active"`
+	Demoted bool   `json:"demoted"`
+}
+
+// poolHealth is the LDAP connection pool statistics in the /health response.
+type poolHealth struct {
+	Open                int              `json:"open"`
+	InUse               int              `json:"in_use"`
+	Idle                int              `json:"idle"`
+	ClosedIdleTime      int              `json:"closed_idletime"`
+	ClosedLifeTime      int              `json:"closed_lifetime"`
+	ClosedLiveness      int              `json:"closed_liveness"`
+	WaitCount           int              `json:"wait_count"`
+	WaitDurationSeconds float64          `json:"wait_duration_seconds"`
+	BadConnDiscards     int              `json:"bad_conn_discards"`
+	DialErrors          map[string]int   `json:"dial_errors"`
+	BindErrors          map[string]int   `json:"bind_errors"`
+	AcquireTime         histogramHealth  `json:"acquire_time"`
+	DialTime            histogramHealth  `json:"dial_time"`
+	BindTime            histogramHealth  `json:"bind_time"`
+	Endpoints           []endpointHealth `json:"endpoints"`
+	CircuitState        string           `json:"circuit_state"`
+}
+
+// backendHealth is the discovered backend in the /health response.
+type backendHealth struct {
+	Type      string   `json:"type"`
+	Instances []string `json:"instances"`
+}
+
+// Health serves the exporter health endpoints.
+type Health struct {
+	pool        *expldap.Pool
+	dsCollector *collectors.DSCollector
+	backend     BackendReporter
+	startTime   time.Time
+	timeout     time.Duration
+}
+
+// NewHealth function create new Health instance based on provided parameters.
+func NewHealth(
 	pool *expldap.Pool,
+	dsCollector *collectors.DSCollector,
+	backend BackendReporter,
 	startTime time.Time,
 	timeout time.Duration,
-) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, req *http.Request) {
-
-		ldapStatus := "ok"
-		ldapAvailable := true
-		// Since the pool checks connections before issuing them,
-		// and gives out either a verified (live) connection or a newly established one,
-		// we can assume that if the pool has issued a connection, ldap is available.
-		ldapReq := ldap.NewSearchRequest(
-			"",
-			ldap.ScopeBaseObject,
-			ldap.NeverDerefAliases,
-			1, 0, false,
-			"(objectClass=*)",
-			[]string{"dn"},
-			nil,
-		)
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-		conn, err := pool.Conn(ctx)
-
-		if err != nil {
-			slog.Warn("Healthcheck error", "err", err)
-			ldapStatus = "unavailable"
-			ldapAvailable = false
-		} else {
-			defer conn.Close()
-
-			_, err = conn.Search(ldapReq)
-			if err != nil {
-				slog.Warn("LDAP health check failed", "err", err)
-				ldapStatus = "unavailable"
-				ldapAvailable = false
-			}
-		}
+) *Health {
+	return &Health{
+		pool:        pool,
+		dsCollector: dsCollector,
+		backend:     backend,
+		startTime:   startTime,
+		timeout:     timeout,
+	}
+}
+
+// HealthHttpResponse returns the /health handler.
+// It performs the LDAP healthcheck and returns its json result together with the state of the collectors,
+// the connection pool and the discovered backend. The status code is 503 if LDAP is unavailable.
+func (h *Health) HealthHttpResponse(w http.ResponseWriter, req *http.Request) {
+	err := h.checkLDAP(req.Context())
+
+	healthResponse := h.baseResponse(err)
+	healthResponse["collectors"] = h.collectorsHealth()
+	healthResponse["pool"] = poolStatHealth(h.pool.Stat())
+
+	backendType, instances := "", []string{}
+	if h.backend != nil {
+		backendType, instances = h.backend.Backend()
+	}
+	healthResponse["backend"] = backendHealth{Type: backendType, Instances: instances}
+
+	writeHealthResponse(w, err == nil, healthResponse)
+}
+
+// LiveHttpResponse returns the /health/live handler.
+// The exporter is alive as long as it serves HTTP, LDAP availability does not affect liveness,
+// since restarting the exporter does not help when the directory server is down.
+func (h *Health) LiveHttpResponse(w http.ResponseWriter, _ *http.Request) {
+	writeHealthResponse(w, true, map[string]any{
+		"status":         "ok",
+		"uptime_seconds": int(time.Since(h.startTime).Seconds()),
+		"timestamp":      time.Now().Format(time.RFC3339),
+	})
+}
+
+// ReadyHttpResponse returns the /health/ready handler.
+// The exporter is ready when LDAP is available, otherwise the status code is 503.
+func (h *Health) ReadyHttpResponse(w http.ResponseWriter, req *http.Request) {
+	err := h.checkLDAP(req.Context())
+	writeHealthResponse(w, err == nil, h.baseResponse(err))
+}
 
-		uptime := time.Since(startTime).Seconds()
-		var errMsg string
-		if err == nil {
-			errMsg = ""
-		} else {
-			errMsg = err.Error()
+// checkLDAP checks that a connection can be acquired from the pool and the root DSE can be read.
+func (h *Health) checkLDAP(ctx context.Context) error {
+	// Since the pool checks connections before issuing them,
+	// and gives out either a verified (live) connection or a newly established one,
+	// we can assume that if the pool has issued a connection, ldap is available.
+	ldapReq := ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		1, 0, false,
+		"(objectClass=*)",
+		[]string{"dn"},
+		nil,
+	)
+	ctx, cancel := context.WithTimeout(expldap.WithCollector(ctx, "health"), h.timeout)
+	defer cancel()
+	conn, err := h.pool.Conn(ctx)
+
+	if err != nil {
+		slog.Warn("Healthcheck error", "err", err)
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Search(ctx, ldapReq)
+	if err != nil {
+		slog.Warn("LDAP health check failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// baseResponse returns the fields of the original /health response.
+func (h *Health) baseResponse(err error) map[string]any {
+	ldapStatus := "ok"
+	var errMsg string
+	if err != nil {
+		ldapStatus = "unavailable"
+		errMsg = err.Error()
+	}
+
+	return map[string]any{
+		"status": map[string]string{
+			"ldap": ldapStatus,
+		},
+		"uptime_seconds": int(time.Since(h.startTime).Seconds()),
+		"timestamp":      time.Now().Format(time.RFC3339),
+		"error":          errMsg,
+	}
+}
+
+// collectorsHealth returns the last scrape results of the registered collectors.
+func (h *Health) collectorsHealth() map[string]collectorHealth {
+	result := make(map[string]collectorHealth)
+	if h.dsCollector == nil {
+		return result
+	}
+
+	for name, status := range h.dsCollector.Status() {
+		health := collectorHealth{
+			DurationSeconds: status.Duration.Seconds(),
+			Success:         !status.Time.IsZero() && status.Err == nil,
 		}
-		healthResponse := map[string]any{
-			"status": map[string]string{
-				"ldap": ldapStatus,
-			},
-			"uptime_seconds": int(uptime),
-			"timestamp":      time.Now().Format(time.RFC3339),
-			"error":          errMsg,
+		if !status.Time.IsZero() {
+			health.LastScrape = status.Time.Format(time.RFC3339)
 		}
-
-		if ldapAvailable {
-			w.WriteHeader(http.StatusOK)
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
+		if status.Err != nil {
+			health.Error = status.Err.Error()
 		}
+		result[name] = health
+	}
+	return result
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(w).Encode(healthResponse)
-		if err != nil {
-			slog.Error("Failed to write health response", "err", err)
-		}
+// poolStatHealth converts the pool statistics to the /health representation.
+func poolStatHealth(stat expldap.PoolStat) poolHealth {
+	health := poolHealth{
+		Open:                stat.Open,
+		InUse:               stat.InUse,
+		Idle:                stat.Idle,
+		ClosedIdleTime:      stat.ClosedIdleTime,
+		ClosedLifeTime:      stat.ClosedLifeTime,
+		ClosedLiveness:      stat.ClosedLiveness,
+		WaitCount:           stat.WaitCount,
+		WaitDurationSeconds: stat.WaitDuration.Seconds(),
+		BadConnDiscards:     stat.BadConnDiscards,
+		DialErrors:          resultCodeCounts(stat.DialErrors),
+		BindErrors:          resultCodeCounts(stat.BindErrors),
+		AcquireTime:         histogramHealth{Count: stat.AcquireTime.Count, SumSeconds: stat.AcquireTime.Sum},
+		DialTime:            histogramHealth{Count: stat.DialTime.Count, SumSeconds: stat.DialTime.Sum},
+		BindTime:            histogramHealth{Count: stat.BindTime.Count, SumSeconds: stat.BindTime.Sum},
+		Endpoints:           make([]endpointHealth, 0, len(stat.Endpoints)),
+		CircuitState:        stat.CircuitState.String(),
+	}
+	for _, endpoint := range stat.Endpoints {
+		health.Endpoints = append(health.Endpoints, endpointHealth{
+			URL:     endpoint.URL,
+			Active:  endpoint.Active,
+			Demoted: endpoint.Demoted,
+		})
+	}
+	return health
+}
+
+// resultCodeCounts converts the error counts by LDAP result code to a map by result name.
+func resultCodeCounts(counts map[uint16]int) map[string]int {
+	result := make(map[string]int, len(counts))
+	for code, count := range counts {
+		result[expldap.ResultName(code)] = count
+	}
+	return result
+}
+
+// writeHealthResponse writes the json health response with 200 if healthy, otherwise with 503.
+func writeHealthResponse(w http.ResponseWriter, healthy bool, response map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	if healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		slog.Error("Failed to write health response", "err", err)
 	}
 }
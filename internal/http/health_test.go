@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/collectors"
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// fakeConn is an LDAP connection answering every search with an empty result or the configured error.
+type fakeConn struct {
+	err error
+}
+
+func (f *fakeConn) Bind(_ expldap.AuthConfig) error { return nil }
+func (f *fakeConn) Unbind() error                   { return nil }
+func (f *fakeConn) Close() error                    { return nil }
+
+func (f *fakeConn) Search(_ context.Context, _ *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ldap.SearchResult{}, nil
+}
+
+// fakeBackend reports a fixed backend.
+type fakeBackend struct{}
+
+func (fakeBackend) Backend() (string, []string) { return "lmdb", []string{"userroot"} }
+
+// failingCollector always fails.
+type failingCollector struct{}
+
+func (failingCollector) Get(_ context.Context, _ chan<- prometheus.Metric) error {
+	return errors.New("no such object")
+}
+
+func setupHealth(t *testing.T, conn *fakeConn) *Health {
+	t.Helper()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return conn, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ds := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	ds.Register("failing", failingCollector{})
+	_, _ = ds.Gatherer(context.Background()).Gather()
+
+	return NewHealth(pool, ds, fakeBackend{}, time.Now(), time.Second)
+}
+
+func serveHealth(handler http.HandlerFunc, path string) (int, map[string]any) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", path, nil))
+
+	var body map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	health := setupHealth(t, &fakeConn{})
+
+	code, body := serveHealth(health.HealthHttpResponse, "/health")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]any{"ldap": "ok"}, body["status"])
+	require.Contains(t, body, "uptime_seconds")
+	require.Contains(t, body, "timestamp")
+	require.Equal(t, "", body["error"])
+
+	failing := body["collectors"].(map[string]any)["failing"].(map[string]any)
+	require.Equal(t, false, failing["success"])
+	require.Equal(t, "no such object", failing["error"])
+	require.NotEmpty(t, failing["last_scrape"])
+
+	pool := body["pool"].(map[string]any)
+	require.InDelta(t, 1, pool["open"], 0)
+	require.Equal(t, "closed", pool["circuit_state"])
+
+	require.Equal(t, map[string]any{"type": "lmdb", "instances": []any{"userroot"}}, body["backend"])
+}
+
+func TestHealthLiveReady(t *testing.T) {
+	conn := &fakeConn{err: ldap.NewError(ldap.LDAPResultUnavailable, errors.New("unavailable"))}
+	health := setupHealth(t, conn)
+
+	code, body := serveHealth(health.LiveHttpResponse, "/health/live")
+	require.Equal(t, http.StatusOK, code, "LDAP availability should not affect liveness")
+	require.Equal(t, "ok", body["status"])
+
+	code, body = serveHealth(health.ReadyHttpResponse, "/health/ready")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, map[string]any{"ldap": "unavailable"}, body["status"])
+	require.NotEmpty(t, body["error"])
+
+	code, _ = serveHealth(health.HealthHttpResponse, "/health")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	searches := health.pool.Stat().Searches
+	require.Contains(t, searches, "health", "The health check searches should be recorded under the health collector")
+	require.NotContains(t, searches, "")
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"389-ds-exporter/internal/collectors"
+	"389-ds-exporter/internal/tracing"
+)
+
+// scrapeTimeoutHeader is the header in which Prometheus sends the scrape timeout.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// MetricsHttpHandler returns the metrics handler.
+// For every request the DSCollector is bound to a context whose deadline is the Prometheus scrape timeout
+// minus timeoutOffset, so that the exporter answers before Prometheus gives up.
+// The collect[] and exclude[] query parameters select the collectors to run, for example
+// /metrics?collect[]=server&collect[]=snmp-server&exclude[]=ldbm-instance_*.
+// Every request is traced with a scrape span continuing the trace of the request headers.
+func MetricsHttpHandler(
+	registry prometheus.Gatherer,
+	dsCollector *collectors.DSCollector,
+	timeoutOffset time.Duration,
+	opts promhttp.HandlerOpts,
+) http.Handler {
+	return scrapeHandler(registry, dsCollector, timeoutOffset,
+		func(w http.ResponseWriter, req *http.Request, gatherer prometheus.Gatherer) {
+			promhttp.HandlerFor(gatherer, opts).ServeHTTP(w, req)
+		})
+}
+
+// scrapeHandler returns the handler selecting, binding and tracing the collectors like MetricsHttpHandler
+// and passing the gatherer of the registry and the selected collectors to render.
+func scrapeHandler(
+	registry prometheus.Gatherer,
+	dsCollector *collectors.DSCollector,
+	timeoutOffset time.Duration,
+	render func(w http.ResponseWriter, req *http.Request, gatherer prometheus.Gatherer),
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracing.Tracer().Start(ctx, "scrape", trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.path", req.URL.Path),
+				attribute.String("url.query", req.URL.RawQuery),
+			))
+		defer span.End()
+
+		query := req.URL.Query()
+		filtered, err := dsCollector.Filter(query["collect[]"], query["exclude[]"])
+		if err != nil {
+			slog.Warn("Invalid collectors selection", "query", req.URL.RawQuery, "err", err)
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		timeout, ok := scrapeTimeout(req, timeoutOffset)
+		if ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		render(w, req, prometheus.Gatherers{registry, filtered.Gatherer(ctx)})
+	})
+}
+
+// scrapeTimeout returns the time the scrape may take according to the Prometheus scrape timeout header.
+// If the offset is not less than the timeout, the timeout is used as is.
+func scrapeTimeout(req *http.Request, offset time.Duration) (time.Duration, bool) {
+	header := req.Header.Get(scrapeTimeoutHeader)
+	if header == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		slog.Warn("Invalid scrape timeout header", "header", scrapeTimeoutHeader, "value", header)
+		return 0, false
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if offset > 0 && offset < timeout {
+		timeout -= offset
+	}
+
+	return timeout, true
+}
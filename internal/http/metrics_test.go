@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"389-ds-exporter/internal/collectors"
+)
+
+func TestScrapeTimeout(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	_, ok := scrapeTimeout(req, time.Second)
+	require.False(t, ok, "Request without the header should not have a timeout")
+
+	req.Header.Set(scrapeTimeoutHeader, "10")
+	timeout, ok := scrapeTimeout(req, 500*time.Millisecond)
+	require.True(t, ok)
+	require.Equal(t, 9500*time.Millisecond, timeout, "Offset should be subtracted from the scrape timeout")
+
+	req.Header.Set(scrapeTimeoutHeader, "0.5")
+	timeout, ok = scrapeTimeout(req, time.Second)
+	require.True(t, ok)
+	require.Equal(t, 500*time.Millisecond, timeout, "Offset larger than the timeout should be ignored")
+
+	req.Header.Set(scrapeTimeoutHeader, "abc")
+	_, ok = scrapeTimeout(req, time.Second)
+	require.False(t, ok, "Invalid header should be ignored")
+}
+
+func TestMetricsHandlerCollectorSelection(t *testing.T) {
+	ds := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	handler := MetricsHttpHandler(prometheus.NewRegistry(), ds, 0, promhttp.HandlerOpts{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?collect[]=unknown", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "Unknown collector should result in 400")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?exclude[]=ldbm-instance_*", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMetricsHandlerScrapeSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	ds := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	handler := MetricsHttpHandler(prometheus.NewRegistry(), ds, 0, promhttp.HandlerOpts{})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "scrape", spans[0].Name())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String(),
+		"The scrape span should continue the trace of the request")
+}
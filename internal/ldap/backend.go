@@ -1,6 +1,7 @@
 package ldap
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -9,7 +10,7 @@ import (
 )
 
 // GetLdapBackendType gets backend parameters from ldap and returns them as a BackendType.
-func GetLdapBackendType(conn *PoolConn) (*string, error) {
+func GetLdapBackendType(ctx context.Context, conn *PoolConn) (*string, error) {
 	if conn == nil {
 		return nil, errors.New("connection is nil")
 	}
@@ -25,17 +26,24 @@ func GetLdapBackendType(conn *PoolConn) (*string, error) {
 		nil,
 	)
 
-	searchResult, err := conn.Search(searchAttributesRequest)
+	searchResult, err := conn.Search(ctx, searchAttributesRequest)
 	if err != nil {
 		return nil, fmt.Errorf("error determining backend type: %w", err)
 	}
 
+	if len(searchResult.Entries) == 0 {
+		return nil, fmt.Errorf(
+			"error determining backend type: %s is not readable",
+			searchAttributesRequest.BaseDN,
+		)
+	}
+
 	result := searchResult.Entries[0].GetAttributeValue("nsslapd-backend-implement")
 	return &result, nil
 }
 
 // GetLdapBackendInstances gets backend instances from ldap and returns them as []string.
-func GetLdapBackendInstances(conn *PoolConn) ([]string, error) {
+func GetLdapBackendInstances(ctx context.Context, conn *PoolConn) ([]string, error) {
 	if conn == nil {
 		return nil, errors.New("connection is nil")
 	}
@@ -51,7 +59,7 @@ func GetLdapBackendInstances(conn *PoolConn) ([]string, error) {
 		nil,
 	)
 
-	searchResult, err := conn.Search(searchAttributesRequest)
+	searchResult, err := conn.Search(ctx, searchAttributesRequest)
 	if err != nil {
 		return nil, fmt.Errorf("error searching for backend instances: %w", err)
 	}
@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen means that the pool does not dial the LDAP server because the previous dials failed.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitState is the state of the pool circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed means that new connections are dialled as usual.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means that new connections fail fast until the backoff expires.
+	CircuitOpen
+	// CircuitHalfOpen means that a single trial dial is in progress after the backoff expired.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// CircuitOpenError is returned instead of dialing while the circuit is open.
+// It matches ErrCircuitOpen with errors.Is and unwraps to the error of the last failed dial.
+type CircuitOpenError struct {
+	RetryAt time.Time // time of the next trial dial
+	Err     error     // error of the last failed dial
+}
+
+// Error returns the error message.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v until %s after: %v", ErrCircuitOpen, e.RetryAt.Format(time.RFC3339), e.Err)
+}
+
+// Is reports whether the target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// Unwrap returns the error of the last failed dial.
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Err
+}
+
+// breaker is a circuit breaker with exponential backoff and jitter.
+// After a failed dial the circuit opens for a backoff that doubles with every consecutive failure,
+// up to maxBackoff. When the backoff expires a single trial dial is allowed, which closes
+// the circuit on success and opens it again on failure.
+type breaker struct {
+	initialBackoff time.Duration // zero disables the breaker
+	maxBackoff     time.Duration
+
+	mu        sync.Mutex // protects the following fields
+	state     CircuitState
+	failures  int
+	retryAt   time.Time
+	lastError error
+}
+
+// newBreaker creates the circuit breaker.
+func newBreaker(initialBackoff, maxBackoff time.Duration) *breaker {
+	return &breaker{
+		initialBackoff: initialBackoff,
+		maxBackoff:     max(initialBackoff, maxBackoff),
+	}
+}
+
+// allow returns a *CircuitOpenError if a dial is not allowed now.
+func (b *breaker) allow() error {
+	if b.initialBackoff <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitClosed:
+		return nil
+	case CircuitOpen:
+		if time.Now().Before(b.retryAt) {
+			return &CircuitOpenError{RetryAt: b.retryAt, Err: b.lastError}
+		}
+		b.state = CircuitHalfOpen
+		slog.Info("LDAP circuit half-open, trying to dial")
+		return nil
+	case CircuitHalfOpen:
+		// Only the trial dial may proceed
+		return &CircuitOpenError{RetryAt: b.retryAt, Err: b.lastError}
+	}
+	return nil
+}
+
+// success closes the circuit.
+func (b *breaker) success() {
+	if b.initialBackoff <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != CircuitClosed {
+		slog.Info("LDAP circuit closed", "failures", b.failures)
+	}
+	b.state = CircuitClosed
+	b.failures = 0
+	b.lastError = nil
+}
+
+// failure opens the circuit for the next backoff.
+func (b *breaker) failure(err error) {
+	if b.initialBackoff <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	backoff := b.backoff()
+	b.state = CircuitOpen
+	b.retryAt = time.Now().Add(backoff)
+	b.lastError = err
+	slog.Warn("LDAP circuit opened", "failures", b.failures, "retry_in", backoff, "err", err)
+}
+
+// abort lets the next dial be the trial one if the trial dial was cancelled before it could fail or succeed.
+func (b *breaker) abort() {
+	if b.initialBackoff <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.state = CircuitOpen
+	}
+}
+
+// backoff returns the backoff for the current number of failures:
+// a random duration between half and all of initialBackoff * 2^(failures-1), capped at maxBackoff.
+// The caller must hold the lock.
+func (b *breaker) backoff() time.Duration {
+	backoff := b.maxBackoff
+	if shift := b.failures - 1; shift < 32 {
+		exponential := b.initialBackoff << shift
+		if exponential > 0 && exponential < b.maxBackoff {
+			backoff = exponential
+		}
+	}
+
+	half := backoff / 2
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(backoff-half)+1))
+	if err != nil {
+		return backoff
+	}
+	return half + time.Duration(jitter.Int64())
+}
+
+// stat returns the state of the circuit.
+func (b *breaker) stat() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
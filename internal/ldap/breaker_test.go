@@ -0,0 +1,71 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	var dials atomic.Int64
+	down.Store(true)
+	factory := func(_ *AuthConfig) (Conn, error) {
+		dials.Add(1)
+		if down.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return &fakeLDAP{}, nil
+	}
+	p := NewLDAPPool(PoolConfig{
+		ConnFactory:           factory,
+		MaxConnections:        2,
+		BreakerInitialBackoff: 20 * time.Millisecond,
+		BreakerMaxBackoff:     time.Second,
+	})
+	defer p.Close()
+	ctx := context.Background()
+
+	_, err := p.Conn(ctx)
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("first failed dial should return the dial error, got %v", err)
+	}
+	if p.Stat().CircuitState != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", p.Stat().CircuitState)
+	}
+
+	_, err = p.Conn(ctx)
+	var openErr *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openErr) {
+		t.Fatalf("expected fail fast with CircuitOpenError, got %v", err)
+	}
+	if dials.Load() != 1 {
+		t.Fatalf("open circuit should not dial, dials: %d", dials.Load())
+	}
+
+	down.Store(false)
+	time.Sleep(time.Until(openErr.RetryAt) + time.Millisecond)
+
+	c, err := p.Conn(ctx)
+	if err != nil {
+		t.Fatalf("trial dial after backoff should succeed: %v", err)
+	}
+	c.Close()
+	if p.Stat().CircuitState != CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", p.Stat().CircuitState)
+	}
+}
+
+func TestBreakerBackoff(t *testing.T) {
+	b := newBreaker(time.Second, 10*time.Second)
+	limits := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for failures, limit := range limits {
+		b.failures = failures + 1
+		backoff := b.backoff()
+		if backoff < limit/2 || backoff > limit {
+			t.Fatalf("backoff after %d failures should be within [%s, %s], got %s", b.failures, limit/2, limit, backoff)
+		}
+	}
+}
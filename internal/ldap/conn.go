@@ -1,10 +1,13 @@
 package ldap
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"math"
 	"net"
 	"net/url"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 )
@@ -21,8 +24,50 @@ func (c *RealLdapConn) Bind(auth AuthConfig) error {
 }
 
 // Search executes the given LDAP search request and returns the result.
-func (c *RealLdapConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
-	return c.conn.Search(req)
+// If ctx has a deadline, the server is asked to limit the operation time accordingly.
+// When ctx is done, the search stops waiting for the server and returns the context error.
+// go-ldap does not expose the message ID needed to send an Abandon request,
+// so the caller is expected to discard the connection: unbinding it makes the server
+// abandon all outstanding operations of the connection.
+func (c *RealLdapConn) Search(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeLimit := max(1, int(math.Ceil(time.Until(deadline).Seconds())))
+		if req.TimeLimit == 0 || req.TimeLimit > timeLimit {
+			limited := *req
+			limited.TimeLimit = timeLimit
+			req = &limited
+		}
+	}
+
+	result := &ldap.SearchResult{
+		Entries:   make([]*ldap.Entry, 0),
+		Referrals: make([]string, 0),
+		Controls:  make([]ldap.Control, 0),
+	}
+
+	response := c.conn.SearchAsync(ctx, req, 0)
+	for response.Next() {
+		if entry := response.Entry(); entry != nil {
+			result.Entries = append(result.Entries, entry)
+		}
+		if referral := response.Referral(); referral != "" {
+			result.Referrals = append(result.Referrals, referral)
+		}
+		result.Controls = append(result.Controls, response.Controls()...)
+	}
+
+	err := response.Err()
+	if err != nil {
+		return result, err
+	}
+
+	// SearchAsync stops silently when the context is done
+	err = ctx.Err()
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
 }
 
 // Unbind closes the LDAP connection.
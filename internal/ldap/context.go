@@ -0,0 +1,17 @@
+package ldap
+
+import "context"
+
+// collectorKey is the context key of the name of the collector issuing the LDAP operations.
+type collectorKey struct{}
+
+// WithCollector returns a context carrying the name of the collector issuing the LDAP operations.
+func WithCollector(ctx context.Context, collector string) context.Context {
+	return context.WithValue(ctx, collectorKey{}, collector)
+}
+
+// CollectorFromContext returns the name of the collector carried by ctx, or an empty string.
+func CollectorFromContext(ctx context.Context) string {
+	collector, _ := ctx.Value(collectorKey{}).(string)
+	return collector
+}
@@ -0,0 +1,134 @@
+package ldap
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// FailoverStrategy determines the order in which the pool dials the LDAP server URLs.
+type FailoverStrategy string
+
+const (
+	// FailoverOrdered dials the URLs in the configured order, so the first healthy URL is always preferred.
+	FailoverOrdered FailoverStrategy = "ordered"
+	// FailoverRoundRobin starts every dial with the URL following the one used for the previous dial.
+	FailoverRoundRobin FailoverStrategy = "round-robin"
+)
+
+// endpoint stores the health of a single LDAP server URL.
+type endpoint struct {
+	url          string
+	demotedUntil time.Time // the endpoint is tried only after the healthy ones until this time
+}
+
+// endpointSet selects the LDAP server URLs to dial.
+// Endpoints that failed are demoted for demotionTime: they are tried only when
+// all healthy endpoints have failed too.
+type endpointSet struct {
+	strategy     FailoverStrategy
+	demotionTime time.Duration
+
+	mu        sync.Mutex // protects the following fields
+	endpoints []*endpoint
+	next      int    // index of the first endpoint of the next round-robin dial
+	active    string // URL of the endpoint that served the last successful operation
+}
+
+// newEndpointSet creates the endpoint set for the given URLs.
+func newEndpointSet(urls []string, strategy FailoverStrategy, demotionTime time.Duration) *endpointSet {
+	set := &endpointSet{
+		strategy:     strategy,
+		demotionTime: demotionTime,
+	}
+	for _, url := range urls {
+		set.endpoints = append(set.endpoints, &endpoint{url: url})
+	}
+	return set
+}
+
+// candidates returns the URLs in the order they should be dialled:
+// healthy endpoints according to the strategy, followed by the demoted ones
+// starting with the one whose demotion ends first.
+func (s *endpointSet) candidates() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ordered := s.endpoints
+	if s.strategy == FailoverRoundRobin && len(s.endpoints) > 0 {
+		start := s.next % len(s.endpoints)
+		ordered = slices.Concat(s.endpoints[start:], s.endpoints[:start])
+		s.next = start + 1
+	}
+
+	now := time.Now()
+	var healthy, demoted []*endpoint
+	for _, e := range ordered {
+		if e.demotedUntil.After(now) {
+			demoted = append(demoted, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	slices.SortStableFunc(demoted, func(a, b *endpoint) int {
+		return a.demotedUntil.Compare(b.demotedUntil)
+	})
+
+	urls := make([]string, 0, len(ordered))
+	for _, e := range slices.Concat(healthy, demoted) {
+		urls = append(urls, e.url)
+	}
+	return urls
+}
+
+// demote marks the endpoint as failing.
+func (s *endpointSet) demote(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.endpoints {
+		if e.url == url {
+			e.demotedUntil = time.Now().Add(s.demotionTime)
+		}
+	}
+	if s.active == url {
+		s.active = ""
+	}
+}
+
+// promote marks the endpoint as healthy and active.
+func (s *endpointSet) promote(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.endpoints {
+		if e.url == url {
+			e.demotedUntil = time.Time{}
+		}
+	}
+	s.active = url
+}
+
+// setActive records the endpoint that served the last successful operation.
+func (s *endpointSet) setActive(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = url
+}
+
+// stat returns the state of the endpoints.
+func (s *endpointSet) stat() []EndpointStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]EndpointStat, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		stats = append(stats, EndpointStat{
+			URL:     e.url,
+			Active:  e.url == s.active,
+			Demoted: e.demotedUntil.After(now),
+		})
+	}
+	return stats
+}
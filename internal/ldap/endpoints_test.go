@@ -0,0 +1,147 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// makeFailoverPool creates a pool whose factory fails for the URLs in down and records dialled URLs.
+func makeFailoverPool(t *testing.T, cfg PoolConfig, down *sync.Map) (*Pool, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var dialled []string
+	cfg.ConnFactory = func(auth *AuthConfig) (Conn, error) {
+		mu.Lock()
+		dialled = append(dialled, auth.URL)
+		mu.Unlock()
+		if _, ok := down.Load(auth.URL); ok {
+			return nil, errors.New("connection refused")
+		}
+		return &fakeLDAP{}, nil
+	}
+	cfg.MaxConnections = 4
+	return NewLDAPPool(cfg), &dialled
+}
+
+func activeEndpoint(p *Pool) string {
+	for _, e := range p.Stat().Endpoints {
+		if e.Active {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+func TestOrderedFailover(t *testing.T) {
+	down := &sync.Map{}
+	down.Store("ldap://a", true)
+	p, dialled := makeFailoverPool(t, PoolConfig{
+		URLs:         []string{"ldap://a", "ldap://b"},
+		DemotionTime: time.Hour,
+	}, down)
+	defer p.Close()
+
+	c, err := p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	if c.conn.url != "ldap://b" {
+		t.Fatalf("expected failover to ldap://b, got %s", c.conn.url)
+	}
+	if activeEndpoint(p) != "ldap://b" {
+		t.Fatalf("expected ldap://b to be active, got %q", activeEndpoint(p))
+	}
+
+	// the demoted endpoint should not be dialled first while healthy ones exist
+	c2, err := p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn2: %v", err)
+	}
+	if want := []string{"ldap://a", "ldap://b", "ldap://b"}; !slices.Equal(*dialled, want) {
+		t.Fatalf("unexpected dial order %v, want %v", *dialled, want)
+	}
+	c.Close()
+	c2.Close()
+}
+
+func TestDemotedEndpointIsLastResort(t *testing.T) {
+	down := &sync.Map{}
+	down.Store("ldap://a", true)
+	p, _ := makeFailoverPool(t, PoolConfig{
+		URLs:         []string{"ldap://a", "ldap://b"},
+		DemotionTime: time.Hour,
+	}, down)
+	defer p.Close()
+
+	c, err := p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	c.Close()
+
+	// ldap://b breaks during a search, ldap://a is back but still demoted
+	le := &ldap.Error{ResultCode: ldap.ErrorNetwork, Err: errors.New("net")}
+	c, _ = p.Conn(context.Background())
+	c.conn.conn.(*fakeLDAP).searchErr.Store(le)
+	c.conn.conn.(*fakeLDAP).hasErr.Store(true)
+	_, _ = c.Search(context.Background(), &ldap.SearchRequest{})
+	c.Close()
+
+	down.Delete("ldap://a")
+	down.Store("ldap://b", true)
+
+	c, err = p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("demoted endpoints should be tried when all endpoints failed: %v", err)
+	}
+	if c.conn.url != "ldap://a" {
+		t.Fatalf("expected ldap://a, got %s", c.conn.url)
+	}
+	c.Close()
+}
+
+func TestRoundRobinFailover(t *testing.T) {
+	p, dialled := makeFailoverPool(t, PoolConfig{
+		URLs:             []string{"ldap://a", "ldap://b", "ldap://c"},
+		FailoverStrategy: FailoverRoundRobin,
+	}, &sync.Map{})
+	defer p.Close()
+
+	var conns []*PoolConn
+	for range 4 {
+		c, err := p.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("conn: %v", err)
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+
+	if want := []string{"ldap://a", "ldap://b", "ldap://c", "ldap://a"}; !slices.Equal(*dialled, want) {
+		t.Fatalf("unexpected dial order %v, want %v", *dialled, want)
+	}
+}
+
+func TestAllEndpointsDown(t *testing.T) {
+	down := &sync.Map{}
+	down.Store("ldap://a", true)
+	down.Store("ldap://b", true)
+	p, _ := makeFailoverPool(t, PoolConfig{URLs: []string{"ldap://a", "ldap://b"}}, down)
+	defer p.Close()
+
+	_, err := p.Conn(context.Background())
+	if err == nil {
+		t.Fatalf("expected error when all endpoints are down")
+	}
+	if p.Stat().Open != 0 {
+		t.Fatalf("failed dials should not be counted as open connections")
+	}
+}
@@ -0,0 +1,70 @@
+package ldap
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultBuckets returns the upper bounds (in seconds) of the pool duration histograms.
+func defaultBuckets() []float64 {
+	return []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+}
+
+// HistogramStat provides a structure for storing a snapshot of a duration histogram.
+type HistogramStat struct {
+	Count   uint64
+	Sum     float64            // in seconds
+	Buckets map[float64]uint64 // cumulative counts by upper bound in seconds
+}
+
+// histogram is a minimal duration histogram, so that the ldap package does not depend on Prometheus.
+type histogram struct {
+	mu      sync.Mutex // protects the following fields
+	bounds  []float64
+	counts  []uint64 // non-cumulative counts by bucket, the last one is +Inf
+	count   uint64
+	sumSecs float64
+}
+
+// newHistogram creates a histogram with the default buckets.
+func newHistogram() *histogram {
+	bounds := defaultBuckets()
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// observe adds the duration to the histogram.
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.bounds) && seconds > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sumSecs += seconds
+}
+
+// stat returns a snapshot of the histogram.
+func (h *histogram) stat() HistogramStat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stat := HistogramStat{
+		Count:   h.count,
+		Sum:     h.sumSecs,
+		Buckets: make(map[float64]uint64, len(h.bounds)),
+	}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		stat.Buckets[bound] = cumulative
+	}
+	return stat
+}
@@ -0,0 +1,188 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// refillInterval is the interval of the idle connections refill when the liveness check is disabled.
+	refillInterval = 10 * time.Second
+	// defaultCheckTimeout is the liveness check timeout used when the dial timeout is not set.
+	defaultCheckTimeout = 5 * time.Second
+)
+
+// maintainer pre-warms the pool, refills the idle connections up to MinIdle and checks
+// the liveness of the idle connections until the pool is closed.
+func (pool *Pool) maintainer() {
+	interval := pool.cfg.IdleCheckInterval
+	if interval <= 0 {
+		interval = refillInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-pool.maintainerCh
+		cancel()
+	}()
+
+	pool.refill(ctx)
+
+	for {
+		select {
+		case <-pool.maintainerCh:
+			return
+		case <-pool.refillCh:
+		case <-ticker.C:
+			if pool.cfg.IdleCheckInterval > 0 {
+				pool.checkIdle(ctx)
+			}
+		}
+		pool.refill(ctx)
+	}
+}
+
+// requestRefill signals the maintainer that idle connections may need refilling.
+func (pool *Pool) requestRefill() {
+	if pool.refillCh == nil {
+		return
+	}
+	select {
+	case pool.refillCh <- struct{}{}:
+	default:
+	}
+}
+
+// refill opens new connections until there are MinIdle idle connections or the connection limit is reached.
+func (pool *Pool) refill(ctx context.Context) {
+	for {
+		pool.mu.Lock()
+		if pool.closed || len(pool.freeConns) >= pool.minIdle || (pool.maxOpen > 0 && pool.numOpen >= pool.maxOpen) {
+			pool.mu.Unlock()
+			return
+		}
+		pool.numOpen++ // optimistically
+		pool.mu.Unlock()
+
+		lc, url, err := pool.dial(ctx)
+		if err != nil {
+			pool.mu.Lock()
+			pool.numOpen-- // correct for earlier optimism
+			pool.mu.Unlock()
+			if !errors.Is(err, ErrCircuitOpen) {
+				slog.Warn("Failed to open idle LDAP connection", "err", err)
+			}
+			return
+		}
+
+		pc := &pooledConn{
+			pool:       pool,
+			conn:       lc,
+			url:        url,
+			createdAt:  time.Now(),
+			returnedAt: time.Now(),
+		}
+		pool.mu.Lock()
+		added := pool.putConnLocked(pc, nil)
+		pool.mu.Unlock()
+		if !added {
+			_ = pc.close()
+			return
+		}
+	}
+}
+
+// checkIdle reads the root DSE on every idle connection and closes the connections that fail.
+// Connections are taken out of the pool one at a time, so that the others remain available.
+// The searches are recorded under the idle-check collector.
+func (pool *Pool) checkIdle(ctx context.Context) {
+	ctx = WithCollector(ctx, "idle-check")
+	timeout := pool.cfg.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	checked := make(map[*pooledConn]bool)
+	for {
+		pool.mu.Lock()
+		if pool.closed {
+			pool.mu.Unlock()
+			return
+		}
+		i := slices.IndexFunc(pool.freeConns, func(pc *pooledConn) bool { return !checked[pc] })
+		if i < 0 {
+			pool.mu.Unlock()
+			return
+		}
+		pc := pool.freeConns[i]
+		pool.freeConns = slices.Delete(pool.freeConns, i, i+1)
+		pc.inUse = true
+		pool.mu.Unlock()
+
+		checked[pc] = true
+
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := pool.readRootDSE(checkCtx, pc)
+		cancel()
+
+		if err != nil {
+			slog.Debug("Idle LDAP connection failed the liveness check", "url", pc.url, "err", err)
+			if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+				pool.endpoints.demote(pc.url)
+			}
+			pool.livenessClosedCount.Add(1)
+			_ = pc.close()
+			continue
+		}
+
+		pool.mu.Lock()
+		pc.inUse = false
+		added := pool.putIdleLocked(pc)
+		pool.mu.Unlock()
+		if !added {
+			_ = pc.close()
+		}
+	}
+}
+
+// readRootDSE performs a cheap search of the root DSE on the connection.
+func (pool *Pool) readRootDSE(ctx context.Context, pc *pooledConn) error {
+	req := ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		1,
+		0,
+		false,
+		"(objectClass=*)",
+		[]string{"1.1"},
+		nil,
+	)
+	start := time.Now()
+	res, err := pc.conn.Search(ctx, req)
+	pool.searches.observe(CollectorFromContext(ctx), time.Since(start), res, err)
+	return err
+}
+
+// putIdleLocked returns a checked connection to the pool without resetting its idle time.
+// The free connections are kept ordered by the time they were returned, as the cleaner expects.
+func (pool *Pool) putIdleLocked(pc *pooledConn) bool {
+	if pool.connRequests.Len() > 0 || pool.closed {
+		return pool.putConnLocked(pc, nil)
+	}
+	i := slices.IndexFunc(pool.freeConns, func(c *pooledConn) bool { return c.returnedAt.After(pc.returnedAt) })
+	if i < 0 {
+		i = len(pool.freeConns)
+	}
+	pool.freeConns = slices.Insert(pool.freeConns, i, pc)
+	pool.startCleanerLocked()
+	return true
+}
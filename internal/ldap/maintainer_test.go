@@ -0,0 +1,119 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// waitFor polls the condition until it holds or the timeout expires.
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return condition()
+}
+
+func TestMinIdlePrewarm(t *testing.T) {
+	var dials atomic.Int64
+	factory := func(_ *AuthConfig) (Conn, error) {
+		dials.Add(1)
+		return &fakeLDAP{}, nil
+	}
+	p := NewLDAPPool(PoolConfig{ConnFactory: factory, MaxConnections: 4, MinIdle: 2})
+	defer p.Close()
+
+	if !waitFor(t, time.Second, func() bool { return p.Stat().Idle == 2 }) {
+		t.Fatalf("expected 2 pre-warmed idle connections, got %d", p.Stat().Idle)
+	}
+
+	c, err := p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	if dials.Load() != 2 {
+		t.Fatalf("acquisition should use a pre-warmed connection, dials: %d", dials.Load())
+	}
+	c.Close()
+}
+
+func TestMinIdleKeptByCleaner(t *testing.T) {
+	factory := func(_ *AuthConfig) (Conn, error) { return &fakeLDAP{}, nil }
+	p := NewLDAPPool(PoolConfig{
+		ConnFactory:    factory,
+		MaxConnections: 4,
+		MinIdle:        1,
+		MaxIdleTime:    time.Millisecond,
+	})
+	defer p.Close()
+
+	conns := make([]*PoolConn, 0, 3)
+	for range 3 {
+		c, err := p.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("conn: %v", err)
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+
+	if !waitFor(t, 3*time.Second, func() bool { return p.Stat().ClosedIdleTime >= 2 }) {
+		t.Fatalf("expected idle connections to be closed, got %d", p.Stat().ClosedIdleTime)
+	}
+	if p.Stat().Idle != 1 {
+		t.Fatalf("cleaner should keep the minimum of idle connections, got %d", p.Stat().Idle)
+	}
+}
+
+func TestIdleLivenessCheck(t *testing.T) {
+	var first atomic.Pointer[fakeLDAP]
+	factory := func(_ *AuthConfig) (Conn, error) {
+		conn := &fakeLDAP{}
+		first.CompareAndSwap(nil, conn)
+		return conn, nil
+	}
+	p := NewLDAPPool(PoolConfig{
+		ConnFactory:       factory,
+		MaxConnections:    2,
+		MinIdle:           1,
+		IdleCheckInterval: 5 * time.Millisecond,
+	})
+	defer p.Close()
+
+	if !waitFor(t, time.Second, func() bool { return p.Stat().Idle == 1 }) {
+		t.Fatalf("expected a pre-warmed idle connection")
+	}
+
+	dead := first.Load()
+	dead.searchErr.Store(&ldap.Error{ResultCode: ldap.ErrorNetwork, Err: errors.New("net")})
+	dead.hasErr.Store(true)
+
+	if !waitFor(t, time.Second, func() bool { return p.Stat().ClosedLiveness == 1 && p.Stat().Idle == 1 }) {
+		t.Fatalf("dead idle connection should be replaced, stat: %+v", p.Stat())
+	}
+
+	c, err := p.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	if c.conn.conn == Conn(dead) {
+		t.Fatalf("dead connection was handed out")
+	}
+	c.Close()
+
+	checks := p.Stat().Searches["idle-check"]
+	if checks.Results[ldap.ErrorNetwork] == 0 {
+		t.Fatalf("expected the liveness searches recorded under idle-check, got %v", checks.Results)
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"math/big"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"389-ds-exporter/internal/tracing"
 )
 
 var (
@@ -28,11 +33,36 @@ type PoolConn struct {
 }
 
 // Search performs a search using a pool connection.
-func (c *PoolConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
-	res, err := c.conn.conn.Search(req)
+// The search is cancelled when ctx is done, and the connection is then discarded
+// so that the server abandons the operation.
+// The duration, the number of returned entries and the result code are recorded
+// for the collector carried by ctx.
+func (c *PoolConn) Search(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ldap.search", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ldap.base_dn", req.BaseDN),
+			attribute.String("ldap.scope", ldap.ScopeMap[req.Scope]),
+			attribute.String("ldap.url", c.conn.url),
+		))
+
+	start := time.Now()
+	res, err := c.conn.conn.Search(ctx, req)
+	c.pool.searches.observe(CollectorFromContext(ctx), time.Since(start), res, err)
+
+	code := resultCode(err)
+	span.SetAttributes(attribute.Int("ldap.result_code", int(code)), attribute.String("ldap.result", ResultName(code)))
+	if res != nil {
+		span.SetAttributes(attribute.Int("ldap.entries", len(res.Entries)))
+	}
+	tracing.EndSpan(span, err)
 	if err != nil && isTransportError(err) {
 		c.conn.markBad()
 	}
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		c.pool.endpoints.demote(c.conn.url)
+	} else if err == nil {
+		c.pool.endpoints.setActive(c.conn.url)
+	}
 	return res, err
 }
 
@@ -45,6 +75,7 @@ type pooledConn struct {
 	pool       *Pool
 	sync.Mutex // protects the following fields
 	conn       Conn
+	url        string // URL of the endpoint the connection is established to
 	closed     bool
 	bad        atomic.Bool
 	inUse      bool
@@ -101,7 +132,7 @@ func isTransportError(err error) bool {
 // It includes basic operations such as binding, searching, and unbinding.
 type Conn interface {
 	Bind(AuthConfig) error
-	Search(*ldap.SearchRequest) (*ldap.SearchResult, error)
+	Search(context.Context, *ldap.SearchRequest) (*ldap.SearchResult, error)
 	Unbind() error
 	Close() error
 }
@@ -123,6 +154,23 @@ type PoolConfig struct {
 	MaxLifeTime    time.Duration
 	DialTimeout    time.Duration
 	ConnFactory    func(*AuthConfig) (Conn, error)
+
+	// URLs is the list of LDAP server URLs to fail over between. Auth.URL is used if it is empty.
+	URLs []string
+	// FailoverStrategy determines the order in which URLs are dialled, FailoverOrdered by default.
+	FailoverStrategy FailoverStrategy
+	// DemotionTime is the time a URL that failed is tried only after the healthy ones.
+	DemotionTime time.Duration
+
+	// MinIdle is the number of idle connections the pool keeps open and refills.
+	MinIdle int
+	// IdleCheckInterval enables the periodic liveness check of idle connections when greater than zero.
+	IdleCheckInterval time.Duration
+
+	// BreakerInitialBackoff is the time new connections fail fast with ErrCircuitOpen after a failed dial.
+	// It doubles with every consecutive failure up to BreakerMaxBackoff. Zero disables the circuit breaker.
+	BreakerInitialBackoff time.Duration
+	BreakerMaxBackoff     time.Duration
 }
 
 // Pool implements a pool of ldap connections.
@@ -135,26 +183,62 @@ type Pool struct {
 	maxOpen      int           // <= 0 means unlimited
 	maxLifetime  time.Duration // maximum amount of time a connection may be reused
 	maxIdleTime  time.Duration // maximum amount of time a connection may be idle before being closed
+	minIdle      int           // number of idle connections kept open
 	closed       bool
 	connRequests connRequestSet
 
-	cleanerCh chan struct{}
+	cleanerCh    chan struct{}
+	maintainerCh chan struct{} // closed when the pool is closed to stop the maintainer
+	refillCh     chan struct{} // signals the maintainer that idle connections may need refilling
 
 	waitCount           atomic.Int64 // number of times, the Conn request waited for free connections.
 	lifeTimeClosedCount atomic.Int64 // number of connections closed by life time expired
 	idleTimeClosedCount atomic.Int64 // number of connections closed by idle time expired
+	livenessClosedCount atomic.Int64 // number of idle connections closed after a failed liveness check
 	waitDuration        atomic.Int64
+	badConnDiscards     atomic.Int64 // number of times a bad connection was discarded and the acquisition retried
+
+	acquireTime *histogram
+	dialTime    *histogram
+	bindTime    *histogram
+
+	searches *searchStats
+
+	errorsMu   sync.Mutex     // protects the following fields
+	dialErrors map[uint16]int // by LDAP result code
+	bindErrors map[uint16]int // by LDAP result code
 
 	connFactory func(*AuthConfig) (Conn, error)
+	endpoints   *endpointSet
+	breaker     *breaker
 }
 
 // PoolStat provides a structure for storing pool metrics.
 type PoolStat struct {
-	Open           int
-	ClosedIdleTime int
-	ClosedLifeTime int
-	WaitCount      int
-	WaitDuration   time.Duration
+	Open            int
+	InUse           int
+	Idle            int
+	ClosedIdleTime  int
+	ClosedLifeTime  int
+	ClosedLiveness  int
+	WaitCount       int
+	WaitDuration    time.Duration
+	BadConnDiscards int
+	DialErrors      map[uint16]int // by LDAP result code
+	BindErrors      map[uint16]int // by LDAP result code
+	AcquireTime     HistogramStat
+	DialTime        HistogramStat
+	BindTime        HistogramStat
+	Searches        map[string]SearchStat // by collector name
+	Endpoints       []EndpointStat
+	CircuitState    CircuitState
+}
+
+// EndpointStat provides a structure for storing the state of an LDAP server URL.
+type EndpointStat struct {
+	URL     string
+	Active  bool // whether the endpoint served the last successful operation
+	Demoted bool // whether the endpoint failed recently
 }
 
 // connReuseStrategy determines how (*pool).conn returns database connections.
@@ -171,13 +255,37 @@ const (
 
 // NewLDAPPool creates new LDAPPool instance.
 func NewLDAPPool(cfg PoolConfig) *Pool {
+	urls := cfg.URLs
+	if len(urls) == 0 {
+		urls = []string{cfg.Auth.URL}
+	}
+	strategy := cfg.FailoverStrategy
+	if strategy == "" {
+		strategy = FailoverOrdered
+	}
+
 	pool := &Pool{
 		cfg:         cfg,
 		connFactory: cfg.ConnFactory,
 		maxLifetime: cfg.MaxLifeTime,
 		maxIdleTime: cfg.MaxIdleTime,
 		maxOpen:     cfg.MaxConnections,
+		minIdle:     cfg.MinIdle,
 		cleanerCh:   nil,
+		endpoints:   newEndpointSet(urls, strategy, cfg.DemotionTime),
+		breaker:     newBreaker(cfg.BreakerInitialBackoff, cfg.BreakerMaxBackoff),
+		acquireTime: newHistogram(),
+		dialTime:    newHistogram(),
+		bindTime:    newHistogram(),
+		searches:    newSearchStats(),
+		dialErrors:  make(map[uint16]int),
+		bindErrors:  make(map[uint16]int),
+	}
+
+	if cfg.MinIdle > 0 || cfg.IdleCheckInterval > 0 {
+		pool.maintainerCh = make(chan struct{})
+		pool.refillCh = make(chan struct{}, 1)
+		go pool.maintainer()
 	}
 
 	return pool
@@ -194,10 +302,14 @@ func (pool *Pool) Conn(ctx context.Context) (*PoolConn, error) {
 	var pc *pooledConn
 	var err error
 
+	ctx, span := tracing.Tracer().Start(ctx, "ldap.pool.acquire")
+	start := time.Now()
 	err = pool.retry(func(strategy connReuseStrategy) error {
 		pc, err = pool.conn(strategy, ctx)
 		return err
 	})
+	pool.acquireTime.observe(time.Since(start))
+	tracing.EndSpan(span, err)
 
 	if err != nil {
 		return nil, err
@@ -236,6 +348,11 @@ func (pool *Pool) Close() error {
 		pool.cleanerCh = nil
 	}
 
+	// stop maintainer
+	if pool.maintainerCh != nil {
+		close(pool.maintainerCh)
+	}
+
 	// close and clear idle connections
 	idle := pool.freeConns
 	pool.freeConns = nil
@@ -259,16 +376,47 @@ func (pool *Pool) Stat() PoolStat {
 
 	pool.mu.Lock()
 	stat.Open = pool.numOpen
+	stat.Idle = len(pool.freeConns)
 	pool.mu.Unlock()
+	stat.InUse = stat.Open - stat.Idle
 
 	stat.WaitCount = int(pool.waitCount.Load())
 	stat.WaitDuration = time.Duration(pool.waitDuration.Load())
 	stat.ClosedIdleTime = int(pool.idleTimeClosedCount.Load())
 	stat.ClosedLifeTime = int(pool.lifeTimeClosedCount.Load())
+	stat.ClosedLiveness = int(pool.livenessClosedCount.Load())
+	stat.BadConnDiscards = int(pool.badConnDiscards.Load())
+
+	pool.errorsMu.Lock()
+	stat.DialErrors = maps.Clone(pool.dialErrors)
+	stat.BindErrors = maps.Clone(pool.bindErrors)
+	pool.errorsMu.Unlock()
+
+	stat.AcquireTime = pool.acquireTime.stat()
+	stat.DialTime = pool.dialTime.stat()
+	stat.BindTime = pool.bindTime.stat()
+	stat.Searches = pool.Searches()
+	stat.Endpoints = pool.Endpoints()
+	stat.CircuitState = pool.CircuitState()
 
 	return stat
 }
 
+// Searches returns the statistics of the searches by collector name.
+func (pool *Pool) Searches() map[string]SearchStat {
+	return pool.searches.stat()
+}
+
+// Endpoints returns the state of the LDAP server URLs.
+func (pool *Pool) Endpoints() []EndpointStat {
+	return pool.endpoints.stat()
+}
+
+// CircuitState returns the state of the circuit breaker.
+func (pool *Pool) CircuitState() CircuitState {
+	return pool.breaker.stat()
+}
+
 //nolint:gocognit,nestif
 func (pool *Pool) conn(strategy connReuseStrategy, ctx context.Context) (*pooledConn, error) {
 	pool.mu.Lock()
@@ -390,24 +538,17 @@ func (pool *Pool) conn(strategy connReuseStrategy, ctx context.Context) (*pooled
 	pool.numOpen++ // optimistically
 	pool.mu.Unlock()
 
-	lc, err := pool.connFactory(&pool.cfg.Auth)
+	lc, url, err := pool.dial(ctx)
 	if err != nil {
 		pool.mu.Lock()
 		pool.numOpen-- // correct for earlier optimism
 		pool.mu.Unlock()
-		return nil, fmt.Errorf("dial failed: %w", err)
-	}
-	err = lc.Bind(pool.cfg.Auth)
-	if err != nil {
-		_ = lc.Close()
-		pool.mu.Lock()
-		pool.numOpen-- // correct for earlier optimism
-		pool.mu.Unlock()
-		return nil, fmt.Errorf("bind failed: %w", err)
+		return nil, err
 	}
 	conn := &pooledConn{
 		pool:       pool,
 		conn:       lc,
+		url:        url,
 		createdAt:  time.Now(),
 		returnedAt: time.Now(),
 		inUse:      true,
@@ -415,6 +556,78 @@ func (pool *Pool) conn(strategy connReuseStrategy, ctx context.Context) (*pooled
 	return conn, nil
 }
 
+// dial opens and binds a new connection, trying the endpoints in the failover order
+// until one of them succeeds. Endpoints that fail are demoted.
+func (pool *Pool) dial(ctx context.Context) (Conn, string, error) {
+	err := pool.breaker.allow()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var errs []error
+	for _, url := range pool.endpoints.candidates() {
+		err := ctx.Err()
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		auth := pool.cfg.Auth
+		auth.URL = url
+
+		_, dialSpan := tracing.Tracer().Start(ctx, "ldap.dial", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("ldap.url", url)))
+		start := time.Now()
+		lc, err := pool.connFactory(&auth)
+		pool.dialTime.observe(time.Since(start))
+		tracing.EndSpan(dialSpan, err)
+		if err != nil {
+			pool.countError(pool.dialErrors, err)
+			slog.Warn("LDAP endpoint dial failed", "url", url, "err", err)
+			pool.endpoints.demote(url)
+			errs = append(errs, fmt.Errorf("dial %s failed: %w", url, err))
+			continue
+		}
+
+		_, bindSpan := tracing.Tracer().Start(ctx, "ldap.bind", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("ldap.url", url), attribute.String("ldap.bind_dn", auth.BindDN)))
+		start = time.Now()
+		err = lc.Bind(auth)
+		pool.bindTime.observe(time.Since(start))
+		bindSpan.SetAttributes(attribute.Int("ldap.result_code", int(resultCode(err))))
+		tracing.EndSpan(bindSpan, err)
+		if err != nil {
+			pool.countError(pool.bindErrors, err)
+			_ = lc.Close()
+			slog.Warn("LDAP endpoint bind failed", "url", url, "err", err)
+			pool.endpoints.demote(url)
+			errs = append(errs, fmt.Errorf("bind %s failed: %w", url, err))
+			continue
+		}
+
+		pool.endpoints.promote(url)
+		pool.breaker.success()
+		return lc, url, nil
+	}
+
+	err = errors.Join(errs...)
+	if ctx.Err() != nil {
+		pool.breaker.abort()
+	} else {
+		pool.breaker.failure(err)
+	}
+	return nil, "", err
+}
+
+// countError increments the error counter by the LDAP result code of err.
+func (pool *Pool) countError(counter map[uint16]int, err error) {
+	code := resultCode(err)
+
+	pool.errorsMu.Lock()
+	counter[code]++
+	pool.errorsMu.Unlock()
+}
+
 func (pool *Pool) putConn(pc *pooledConn) {
 	var err error
 	pool.mu.Lock()
@@ -440,6 +653,7 @@ func (pool *Pool) putConn(pc *pooledConn) {
 		// pool.maybeOpenNewConnections()
 		pool.mu.Unlock()
 		_ = pc.close()
+		pool.requestRefill()
 		return
 	}
 
@@ -497,6 +711,7 @@ func (pool *Pool) retry(fn func(strategy connReuseStrategy) error) error {
 		if err == nil || !errors.Is(err, ErrBadConnection) {
 			return err
 		}
+		pool.badConnDiscards.Add(1)
 	}
 
 	return fn(alwaysNewConn)
@@ -552,6 +767,9 @@ func (pool *Pool) connectionCleaner(d time.Duration) {
 		for _, c := range closing {
 			_ = c.close()
 		}
+		if len(closing) > 0 {
+			pool.requestRefill()
+		}
 
 		if d < minInterval {
 			d = minInterval
@@ -577,21 +795,24 @@ func (pool *Pool) connectionCleanerRunLocked(d time.Duration) (time.Duration, []
 		// As freeConn is ordered by returnedAt process
 		// in reverse order to minimise the work needed.
 		idleSince := time.Now().Add(-pool.maxIdleTime)
+		kept := 0 // number of expired connections kept to hold the minimum of idle connections
 		last := len(pool.freeConns) - 1
 		for i := last; i >= 0; i-- {
 			c := pool.freeConns[i]
 			if c.returnedAt.Before(idleSince) {
 				i++
-				closing = pool.freeConns[:i:i]
-				pool.freeConns = pool.freeConns[i:]
+				n := min(i, max(0, len(pool.freeConns)-pool.minIdle))
+				kept = i - n
+				closing = pool.freeConns[:n:n]
+				pool.freeConns = pool.freeConns[n:]
 				idleClosing = int64(len(closing))
 				pool.idleTimeClosedCount.Add(idleClosing)
 				break
 			}
 		}
 
-		if len(pool.freeConns) > 0 {
-			c := pool.freeConns[0]
+		if len(pool.freeConns) > kept {
+			c := pool.freeConns[kept]
 			if d2 := c.returnedAt.Sub(idleSince); d2 < d {
 				// Ensure idle connections are cleaned up as soon as
 				// possible.
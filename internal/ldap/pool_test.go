@@ -19,7 +19,7 @@ type fakeLDAP struct {
 }
 
 func (f *fakeLDAP) Bind(_ AuthConfig) error { f.bindOK = true; return nil }
-func (f *fakeLDAP) Search(_ *ldap.SearchRequest) (*ldap.SearchResult, error) {
+func (f *fakeLDAP) Search(_ context.Context, _ *ldap.SearchRequest) (*ldap.SearchResult, error) {
 	if f.hasErr.Load() {
 		err, _ := f.searchErr.Load().(error)
 		if err != nil {
@@ -53,7 +53,7 @@ func TestBadConnMarkingOnTransportError(t *testing.T) {
 	c.conn.conn.(*fakeLDAP).searchErr.Store(le)
 	c.conn.conn.(*fakeLDAP).hasErr.Store(true)
 
-	_, _ = c.Search(&ldap.SearchRequest{})
+	_, _ = c.Search(ctx, &ldap.SearchRequest{})
 	c.Close()
 
 	// next acquire should not reuse bad connection
@@ -417,7 +417,7 @@ func TestNoLeakOnBadConnUnderConcurrency(t *testing.T) {
 				} else {
 					c.conn.conn.(*fakeLDAP2).hasErr.Store(false)
 				}
-				_, _ = c.Search(&ldap.SearchRequest{})
+				_, _ = c.Search(ctx, &ldap.SearchRequest{})
 				c.Close()
 			}
 		}(i)
@@ -427,3 +427,67 @@ func TestNoLeakOnBadConnUnderConcurrency(t *testing.T) {
 	}
 	_ = p.Close()
 }
+
+// failingBindLDAP fails every bind with invalid credentials.
+type failingBindLDAP struct{ fakeLDAP }
+
+func (f *failingBindLDAP) Bind(_ AuthConfig) error {
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func TestPoolStatInstrumentation(t *testing.T) {
+	var calls int64
+	factory := func(_ *AuthConfig) (Conn, error) {
+		switch atomic.AddInt64(&calls, 1) {
+		case 1:
+			return nil, errors.New("dial fail")
+		case 2:
+			return &failingBindLDAP{}, nil
+		}
+		return &fakeLDAP{}, nil
+	}
+	p := NewLDAPPool(PoolConfig{ConnFactory: factory, MaxConnections: 2})
+	ctx := context.Background()
+
+	_, _ = p.Conn(ctx)
+	_, _ = p.Conn(ctx)
+	c1, err := p.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	c2, err := p.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn2: %v", err)
+	}
+	c2.conn.markBad()
+	c2.Close()
+
+	stat := p.Stat()
+	if stat.InUse != 1 || stat.Idle != 0 {
+		t.Fatalf("expected 1 in use and 0 idle connections, got %d and %d", stat.InUse, stat.Idle)
+	}
+	if stat.DialErrors[ldap.ErrorNetwork] != 1 {
+		t.Fatalf("expected a dial error counted as network error, got %v", stat.DialErrors)
+	}
+	if stat.BindErrors[ldap.LDAPResultInvalidCredentials] != 1 {
+		t.Fatalf("expected a bind error counted by result code, got %v", stat.BindErrors)
+	}
+	if stat.AcquireTime.Count != 4 || stat.DialTime.Count != 4 || stat.BindTime.Count != 3 {
+		t.Fatalf("unexpected histogram counts: acquire %d, dial %d, bind %d",
+			stat.AcquireTime.Count, stat.DialTime.Count, stat.BindTime.Count)
+	}
+
+	c1.Close()
+	c1.conn.markBad()
+	c3, err := p.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn3: %v", err)
+	}
+	c3.Close()
+	if p.Stat().BadConnDiscards != 1 {
+		t.Fatalf("expected a bad connection discard, got %d", p.Stat().BadConnDiscards)
+	}
+	if p.Stat().Idle != 1 {
+		t.Fatalf("expected 1 idle connection, got %d", p.Stat().Idle)
+	}
+}
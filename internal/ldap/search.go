@@ -0,0 +1,140 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SearchStat provides a structure for storing the statistics of the searches issued by a collector.
+type SearchStat struct {
+	Duration HistogramStat
+	Entries  uint64         // number of returned entries
+	Results  map[uint16]int // by LDAP result code
+}
+
+// searchStat accumulates the statistics of the searches issued by a collector.
+type searchStat struct {
+	duration *histogram
+	entries  uint64
+	results  map[uint16]int
+}
+
+// searchStats accumulates the statistics of the pool searches by collector name.
+type searchStats struct {
+	mu         sync.Mutex // protects collectors and the counters of its values
+	collectors map[string]*searchStat
+}
+
+// newSearchStats creates an empty searchStats.
+func newSearchStats() *searchStats {
+	return &searchStats{collectors: make(map[string]*searchStat)}
+}
+
+// observe records a search issued by the collector.
+func (s *searchStats) observe(collector string, duration time.Duration, res *ldap.SearchResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.collectors[collector]
+	if !ok {
+		stat = &searchStat{duration: newHistogram(), results: make(map[uint16]int)}
+		s.collectors[collector] = stat
+	}
+
+	stat.duration.observe(duration)
+	stat.results[resultCode(err)]++
+	if res != nil {
+		stat.entries += uint64(len(res.Entries))
+	}
+}
+
+// stat returns a snapshot of the search statistics by collector name.
+func (s *searchStats) stat() map[string]SearchStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]SearchStat, len(s.collectors))
+	for collector, stat := range s.collectors {
+		stats[collector] = SearchStat{
+			Duration: stat.duration.stat(),
+			Entries:  stat.entries,
+			Results:  maps.Clone(stat.results),
+		}
+	}
+	return stats
+}
+
+// errorCanceled is the client-side result code of the operations canceled by their context,
+// outside of the range of the go-ldap client-side error codes.
+const errorCanceled uint16 = 299
+
+// resultCode returns the LDAP result code of err, which is success for a nil error.
+// Operations that ran out of time count as timeout like the go-ldap response timeout, canceled operations
+// as canceled, and other errors that do not carry a result code as network errors.
+func resultCode(err error) uint16 {
+	switch {
+	case err == nil:
+		return ldap.LDAPResultSuccess
+	case errors.Is(err, context.DeadlineExceeded):
+		return ldap.LDAPResultTimeout
+	case errors.Is(err, context.Canceled):
+		return errorCanceled
+	}
+	var le *ldap.Error
+	if errors.As(err, &le) {
+		return le.ResultCode
+	}
+	return ldap.ErrorNetwork
+}
+
+// resultNames returns the RFC 4511 names of the LDAP result codes and the names of the client-side errors.
+func resultNames() map[uint16]string {
+	return map[uint16]string{
+		ldap.LDAPResultSuccess:                      "success",
+		ldap.LDAPResultOperationsError:              "operationsError",
+		ldap.LDAPResultProtocolError:                "protocolError",
+		ldap.LDAPResultTimeLimitExceeded:            "timeLimitExceeded",
+		ldap.LDAPResultSizeLimitExceeded:            "sizeLimitExceeded",
+		ldap.LDAPResultAuthMethodNotSupported:       "authMethodNotSupported",
+		ldap.LDAPResultStrongAuthRequired:           "strongerAuthRequired",
+		ldap.LDAPResultReferral:                     "referral",
+		ldap.LDAPResultAdminLimitExceeded:           "adminLimitExceeded",
+		ldap.LDAPResultUnavailableCriticalExtension: "unavailableCriticalExtension",
+		ldap.LDAPResultConfidentialityRequired:      "confidentialityRequired",
+		ldap.LDAPResultNoSuchAttribute:              "noSuchAttribute",
+		ldap.LDAPResultUndefinedAttributeType:       "undefinedAttributeType",
+		ldap.LDAPResultInappropriateMatching:        "inappropriateMatching",
+		ldap.LDAPResultInvalidAttributeSyntax:       "invalidAttributeSyntax",
+		ldap.LDAPResultNoSuchObject:                 "noSuchObject",
+		ldap.LDAPResultAliasProblem:                 "aliasProblem",
+		ldap.LDAPResultInvalidDNSyntax:              "invalidDNSyntax",
+		ldap.LDAPResultAliasDereferencingProblem:    "aliasDereferencingProblem",
+		ldap.LDAPResultInappropriateAuthentication:  "inappropriateAuthentication",
+		ldap.LDAPResultInvalidCredentials:           "invalidCredentials",
+		ldap.LDAPResultInsufficientAccessRights:     "insufficientAccessRights",
+		ldap.LDAPResultBusy:                         "busy",
+		ldap.LDAPResultUnavailable:                  "unavailable",
+		ldap.LDAPResultUnwillingToPerform:           "unwillingToPerform",
+		ldap.LDAPResultLoopDetect:                   "loopDetect",
+		ldap.LDAPResultOther:                        "other",
+		ldap.LDAPResultTimeout:                      "timeout",
+		ldap.ErrorNetwork:                           "networkError",
+		errorCanceled:                               "canceled",
+	}
+}
+
+// ResultName returns the name of the LDAP result code, e.g. insufficientAccessRights.
+// Codes without a name are returned as numbers.
+func ResultName(code uint16) string {
+	name, ok := resultNames()[code]
+	if !ok {
+		return strconv.Itoa(int(code))
+	}
+	return name
+}
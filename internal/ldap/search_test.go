@@ -0,0 +1,125 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSearchStatByCollector(t *testing.T) {
+	pool := makePool(t)
+	ctx := WithCollector(context.Background(), "server")
+
+	c, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	_, _ = c.Search(ctx, &ldap.SearchRequest{})
+
+	fake := c.conn.conn.(*fakeLDAP)
+	fake.searchErr.Store(ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("insufficient access")))
+	fake.hasErr.Store(true)
+	_, _ = c.Search(ctx, &ldap.SearchRequest{})
+	_, _ = c.Search(context.Background(), &ldap.SearchRequest{})
+	c.Close()
+
+	stat := pool.Stat().Searches
+	server := stat["server"]
+	if server.Duration.Count != 2 {
+		t.Fatalf("expected 2 searches of the server collector, got %d", server.Duration.Count)
+	}
+	if server.Results[ldap.LDAPResultSuccess] != 1 || server.Results[ldap.LDAPResultInsufficientAccessRights] != 1 {
+		t.Fatalf("expected the searches counted by result code, got %v", server.Results)
+	}
+	if stat[""].Duration.Count != 1 {
+		t.Fatalf("expected a search without collector, got %v", stat)
+	}
+}
+
+func TestResultName(t *testing.T) {
+	for code, name := range map[uint16]string{
+		ldap.LDAPResultSuccess:                  "success",
+		ldap.LDAPResultInsufficientAccessRights: "insufficientAccessRights",
+		ldap.LDAPResultNoSuchObject:             "noSuchObject",
+		ldap.LDAPResultTimeLimitExceeded:        "timeLimitExceeded",
+		ldap.ErrorNetwork:                       "networkError",
+		ldap.LDAPResultCompareTrue:              "6",
+		errorCanceled:                           "canceled",
+	} {
+		if got := ResultName(code); got != name {
+			t.Fatalf("ResultName(%d) = %q, expected %q", code, got, name)
+		}
+	}
+}
+
+func TestResultCode(t *testing.T) {
+	for name, test := range map[string]struct {
+		err  error
+		code uint16
+	}{
+		"success":       {nil, ldap.LDAPResultSuccess},
+		"ldap error":    {ldap.NewError(ldap.LDAPResultBusy, errors.New("busy")), ldap.LDAPResultBusy},
+		"deadline":      {fmt.Errorf("search: %w", context.DeadlineExceeded), ldap.LDAPResultTimeout},
+		"ldap deadline": {ldap.NewError(ldap.ErrorNetwork, context.DeadlineExceeded), ldap.LDAPResultTimeout},
+		"canceled":      {context.Canceled, errorCanceled},
+		"network error": {errors.New("connection reset"), ldap.ErrorNetwork},
+	} {
+		if got := resultCode(test.err); got != test.code {
+			t.Fatalf("%s: resultCode = %d, expected %d", name, got, test.code)
+		}
+	}
+}
+
+func TestSearchSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	pool := NewLDAPPool(PoolConfig{
+		Auth:           AuthConfig{URL: "ldap://localhost:389", BindDN: "cn=exporter"},
+		ConnFactory:    func(_ *AuthConfig) (Conn, error) { return &fakeLDAP{}, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ctx := context.Background()
+	c, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	_, _ = c.Search(ctx, ldap.NewSearchRequest("cn=monitor", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", nil, nil))
+	c.Close()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"ldap.pool.acquire", "ldap.dial", "ldap.bind", "ldap.search"} {
+		if spans[name] == nil {
+			t.Fatalf("expected a %s span, got %v", name, spans)
+		}
+	}
+	if spans["ldap.dial"].Parent().SpanID() != spans["ldap.pool.acquire"].SpanContext().SpanID() {
+		t.Fatalf("expected the dial span to be a child of the acquire span")
+	}
+
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans["ldap.search"].Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	if attributes["ldap.base_dn"].AsString() != "cn=monitor" || attributes["ldap.scope"].AsString() != "Base Object" {
+		t.Fatalf("unexpected search span attributes: %v", attributes)
+	}
+	if attributes["ldap.result"].AsString() != "success" || attributes["ldap.entries"].AsInt64() != 0 {
+		t.Fatalf("unexpected search span result attributes: %v", attributes)
+	}
+}
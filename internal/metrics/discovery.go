@@ -0,0 +1,295 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"389-ds-exporter/internal/collectors"
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// discoveryRetryInterval is the delay before the next discovery after a failed one
+// and the minimum time between discoveries triggered by collector failures.
+const discoveryRetryInterval = 30 * time.Second
+
+// Discoverer detects the backend implementation type and the backend instances of the server
+// and keeps the backend collectors registered in the DSCollector in line with them.
+// Values specified in the configuration are never detected.
+type Discoverer struct {
+	cfg            *config.ExporterConfig
+	connPool       *expldap.Pool
+	dsCollector    *collectors.DSCollector
+	poolGetTimeout time.Duration
+	interval       time.Duration
+	infoDesc       *prometheus.Desc
+
+	running     sync.Mutex // serializes the discoveries
+	mu          sync.Mutex
+	backendType string
+	instances   []string
+	registered  map[string]bool // names of the registered backend collectors
+	lastRun     time.Time
+	failed      bool // whether the last discovery failed
+
+	trigger chan struct{}
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewDiscoverer function create new Discoverer instance based on provided parameters.
+func NewDiscoverer(
+	cfg *config.ExporterConfig,
+	connPool *expldap.Pool,
+	dsCollector *collectors.DSCollector,
+) *Discoverer {
+	return &Discoverer{
+		cfg:            cfg,
+		connPool:       connPool,
+		dsCollector:    dsCollector,
+		poolGetTimeout: time.Duration(cfg.LDAPPoolGetTimeout) * time.Second,
+		interval:       time.Duration(cfg.DSDiscoveryInterval) * time.Second,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("ds", "exporter", "discovered_backend_info"),
+			"Backend instances discovered by the exporter and the backend implementation type",
+			[]string{"database", "type"},
+			nil,
+		),
+		registered: make(map[string]bool),
+		trigger:    make(chan struct{}, 1),
+	}
+}
+
+// Describe Discoverer metrics.
+func (d *Discoverer) Describe(channel chan<- *prometheus.Desc) {
+	channel <- d.infoDesc
+}
+
+// Collect sends the info metric for every discovered backend instance.
+func (d *Discoverer) Collect(channel chan<- prometheus.Metric) {
+	d.mu.Lock()
+	backendType, instances := d.backendType, d.instances
+	d.mu.Unlock()
+
+	for _, instance := range instances {
+		channel <- prometheus.MustNewConstMetric(d.infoDesc, prometheus.GaugeValue, 1, instance, backendType)
+	}
+}
+
+// Discover detects the backend type and instances and registers the corresponding collectors,
+// unregistering the collectors of backends that are gone.
+// If detection of a value fails, its previous value is kept.
+// The LDAP searches run without holding the lock, so that scrapes are not blocked by a slow server.
+func (d *Discoverer) Discover(ctx context.Context) error {
+	d.running.Lock()
+	defer d.running.Unlock()
+
+	started := time.Now()
+	ctx = expldap.WithCollector(ctx, "discovery")
+	backendType, typeErr := determineBackendType(ctx, d.cfg, d.connPool, d.poolGetTimeout)
+	instances, instancesErr := determineBackendInstances(ctx, d.cfg, d.connPool, d.poolGetTimeout)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastRun = started
+	if typeErr != nil {
+		backendType = d.backendType
+	} else if backendType != d.backendType {
+		slog.Info("Backend implementation type discovered", "type", backendType, "previous", d.backendType)
+	}
+
+	if instancesErr != nil {
+		instances = d.instances
+	} else if !slices.Equal(instances, d.instances) {
+		slog.Info("Backend instances discovered", "instances", instances, "previous", d.instances)
+	}
+
+	if backendType != d.backendType && !slices.Contains([]string{config.BackendBDB, config.BackendMDB}, backendType) {
+		slog.Warn(
+			"An unknown backend implementation type was detected. Backend metrics will not be collected",
+			"backend",
+			backendType,
+		)
+	}
+
+	d.backendType, d.instances = backendType, instances
+	d.failed = typeErr != nil || instancesErr != nil
+	d.apply()
+
+	return errors.Join(typeErr, instancesErr)
+}
+
+// Backend returns the discovered backend type and instances.
+func (d *Discoverer) Backend() (string, []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.backendType, slices.Clone(d.instances)
+}
+
+// Trigger requests a discovery from the background loop, for example after a collector failure.
+// Requests made earlier than discoveryRetryInterval after the last discovery are ignored.
+func (d *Discoverer) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts the periodic discovery every ds_discovery_interval.
+// It does nothing if both the backend type and instances are specified in the configuration.
+func (d *Discoverer) Start() {
+	if d.stop != nil || (d.cfg.DSBackendType != "" && len(d.cfg.DSBackendDBs) > 0) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.stop = cancel
+	d.wg.Add(1)
+	go d.loop(ctx)
+}
+
+// Stop stops the periodic discovery and waits for the running discovery to finish.
+func (d *Discoverer) Stop() {
+	if d.stop == nil {
+		return
+	}
+	d.stop()
+	d.wg.Wait()
+	d.stop = nil
+}
+
+// loop runs the discovery on schedule and on trigger until ctx is done.
+// After a failed discovery the next one is scheduled after discoveryRetryInterval.
+func (d *Discoverer) loop(ctx context.Context) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		delay := d.interval
+		if d.failed && (delay == 0 || delay > discoveryRetryInterval) {
+			delay = discoveryRetryInterval
+		}
+		lastRun := d.lastRun
+		d.mu.Unlock()
+
+		var tick <-chan time.Time
+		var timer *time.Timer
+		if delay > 0 {
+			timer = time.NewTimer(time.Until(lastRun.Add(delay)))
+			tick = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-tick:
+		case <-d.trigger:
+			if time.Since(lastRun) < discoveryRetryInterval {
+				slog.Debug("Skipping triggered backend discovery", "last_run", lastRun)
+				if timer != nil {
+					timer.Stop()
+				}
+				continue
+			}
+			slog.Debug("Backend discovery triggered by a collector failure")
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := d.Discover(ctx)
+		if err != nil {
+			slog.Error("Backend discovery failed", "err", err, "retry_in", discoveryRetryInterval)
+		}
+	}
+}
+
+// apply registers the collectors of the discovered backends and unregisters the others.
+// The caller must hold the lock.
+func (d *Discoverer) apply() {
+	wanted := d.backendCollectors()
+
+	for _, name := range slices.Sorted(maps.Keys(d.registered)) {
+		if _, ok := wanted[name]; !ok {
+			slog.Info("Unregistering collector of a removed backend", "collector", name)
+			d.dsCollector.Unregister(name)
+			delete(d.registered, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(wanted)) {
+		if d.registered[name] || !collectorEnabled(d.cfg, name) {
+			continue
+		}
+		slog.Debug("Registering collector", "collector", name)
+		d.dsCollector.Register(name, wanted[name]())
+		d.registered[name] = true
+	}
+}
+
+// backendCollectors returns the factories of the collectors of the discovered backend type and instances by name.
+func (d *Discoverer) backendCollectors() map[string]func() collectors.InternalCollector {
+	result := make(map[string]func() collectors.InternalCollector)
+
+	switch d.backendType {
+	case config.BackendBDB:
+		result["bdb-caches"] = func() collectors.InternalCollector {
+			return collectors.NewLdapEntryCollector(
+				"bdb",
+				d.connPool,
+				"cn=monitor,cn=ldbm database,cn=plugins,cn=config",
+				GetLdapBDBServerCacheMetrics(),
+				prometheus.Labels{},
+				d.poolGetTimeout,
+			)
+		}
+		result["bdb-internal"] = func() collectors.InternalCollector {
+			return collectors.NewLdapEntryCollector(
+				"bdb",
+				d.connPool,
+				"cn=database,cn=monitor,cn=ldbm database,cn=plugins,cn=config",
+				GetLdapBDBDatabaseLDBM(),
+				prometheus.Labels{},
+				d.poolGetTimeout,
+			)
+		}
+	case config.BackendMDB:
+		result["lmdb-internal"] = func() collectors.InternalCollector {
+			return collectors.NewLdapEntryCollector(
+				"lmdb",
+				d.connPool,
+				"cn=database,cn=monitor,cn=ldbm database,cn=plugins,cn=config",
+				GetLdapMDBDatabaseLDBM(),
+				prometheus.Labels{},
+				d.poolGetTimeout,
+			)
+		}
+	}
+
+	for _, instance := range d.instances {
+		result["ldbm-instance_"+instance] = func() collectors.InternalCollector {
+			return collectors.NewLdapEntryCollector(
+				"ldbm_instance",
+				d.connPool,
+				"cn=monitor,cn="+instance+",cn=ldbm database,cn=plugins,cn=config",
+				GetLdapBackendCaches(),
+				prometheus.Labels{"database": instance},
+				d.poolGetTimeout,
+			)
+		}
+	}
+
+	return result
+}
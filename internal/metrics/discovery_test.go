@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/collectors"
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// fakeBackends is an LDAP connection serving the backend configuration entries.
+type fakeBackends struct {
+	mu          sync.Mutex
+	backendType string
+	instances   []string
+	hidden      bool          // whether the backend configuration entry is hidden by the ACIs
+	block       chan struct{} // if not nil, searches wait until it is closed
+}
+
+func (f *fakeBackends) Bind(_ expldap.AuthConfig) error { return nil }
+func (f *fakeBackends) Unbind() error                   { return nil }
+func (f *fakeBackends) Close() error                    { return nil }
+
+func (f *fakeBackends) Search(_ context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.mu.Lock()
+	block := f.block
+	f.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := &ldap.SearchResult{}
+	if req.Scope == ldap.ScopeBaseObject {
+		if f.hidden {
+			return result, nil
+		}
+		entry := ldap.NewEntry(req.BaseDN, map[string][]string{"nsslapd-backend-implement": {f.backendType}})
+		result.Entries = append(result.Entries, entry)
+		return result, nil
+	}
+	for _, instance := range f.instances {
+		entry := ldap.NewEntry("cn="+instance+","+req.BaseDN, map[string][]string{"cn": {instance}})
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+func (f *fakeBackends) set(backendType string, instances ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.backendType = backendType
+	f.instances = instances
+}
+
+func setupDiscoverer(t *testing.T, backends *fakeBackends) (*Discoverer, *collectors.DSCollector) {
+	t.Helper()
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return backends, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	cfg := &config.ExporterConfig{CollectorsDefault: "all", LDAPPoolGetTimeout: 1}
+	ds := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	return NewDiscoverer(cfg, pool, ds), ds
+}
+
+func TestDiscoverBackends(t *testing.T) {
+	backends := &fakeBackends{}
+	backends.set(config.BackendBDB, "userRoot", "changelog")
+	discoverer, ds := setupDiscoverer(t, backends)
+
+	require.NoError(t, discoverer.Discover(context.Background()))
+	require.Equal(t,
+		[]string{"bdb-caches", "bdb-internal", "ldbm-instance_changelog", "ldbm-instance_userRoot"},
+		ds.Names())
+
+	backends.set(config.BackendMDB, "userRoot", "ipaca")
+	require.NoError(t, discoverer.Discover(context.Background()))
+	require.Equal(t,
+		[]string{"ldbm-instance_ipaca", "ldbm-instance_userRoot", "lmdb-internal"},
+		ds.Names(),
+		"Collectors of removed backends should be unregistered")
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(discoverer)
+	require.Equal(t, 2, testutil.CollectAndCount(registry, "ds_exporter_discovered_backend_info"))
+}
+
+func TestDiscoverPinnedValues(t *testing.T) {
+	backends := &fakeBackends{}
+	backends.set(config.BackendBDB, "userRoot", "changelog")
+	discoverer, ds := setupDiscoverer(t, backends)
+	discoverer.cfg.DSBackendType = config.BackendMDB
+	discoverer.cfg.DSBackendDBs = []string{"userRoot"}
+
+	require.NoError(t, discoverer.Discover(context.Background()))
+	require.Equal(t, []string{"ldbm-instance_userRoot", "lmdb-internal"}, ds.Names(),
+		"Values specified in the configuration should not be detected")
+}
+
+func TestDiscoverUnreadableBackendType(t *testing.T) {
+	backends := &fakeBackends{}
+	backends.set(config.BackendMDB, "userRoot")
+	discoverer, _ := setupDiscoverer(t, backends)
+	require.NoError(t, discoverer.Discover(context.Background()))
+
+	backends.mu.Lock()
+	backends.hidden = true
+	backends.mu.Unlock()
+
+	err := discoverer.Discover(context.Background())
+	require.ErrorContains(t, err, "is not readable", "A backend configuration entry hidden by the ACIs should fail")
+	backendType, instances := discoverer.Backend()
+	require.Equal(t, config.BackendMDB, backendType, "The previous backend type should be kept")
+	require.Equal(t, []string{"userRoot"}, instances)
+}
+
+func TestDiscoverDoesNotBlockCollect(t *testing.T) {
+	backends := &fakeBackends{block: make(chan struct{})}
+	backends.set(config.BackendMDB, "userRoot")
+	discoverer, _ := setupDiscoverer(t, backends)
+
+	done := make(chan error)
+	go func() {
+		done <- discoverer.Discover(context.Background())
+	}()
+
+	collected := make(chan int)
+	go func() {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(discoverer)
+		collected <- testutil.CollectAndCount(registry)
+	}()
+
+	select {
+	case count := <-collected:
+		require.Equal(t, 0, count)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Collect should not wait for the LDAP searches of a running discovery")
+	}
+
+	close(backends.block)
+	require.NoError(t, <-done)
+}
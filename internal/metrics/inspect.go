@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"389-ds-exporter/internal/collectors"
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// Entry access states reported by Inspect.
+const (
+	EntryReadable   = "readable"
+	EntryNotPresent = "not present"
+	EntryNoAccess   = "no access"
+	EntryError      = "error"
+)
+
+// EntryAccess describes whether the exporter can read the entry of a collector.
+type EntryAccess struct {
+	Collector string
+	DN        string
+	State     string // one of the Entry* states
+	Err       error  // error of the search, if any
+}
+
+// ServerInfo describes a directory server as seen by the exporter.
+type ServerInfo struct {
+	Version     string
+	BackendType string
+	Backends    []string
+	Suffixes    []string
+	Entries     []EntryAccess
+	Problems    []error // problems that prevent the exporter from reading some information
+}
+
+// Inspect connects to the directory server and reports the backend, suffixes and version of the server
+// and whether the entries of all collectors can be read with the configured bind DN.
+// The backend type and instances are always detected, the values of the configuration are ignored.
+func Inspect(ctx context.Context, cfg *config.ExporterConfig, connPool *expldap.Pool) (*ServerInfo, error) {
+	ctx = expldap.WithCollector(ctx, "inspect")
+	timeout := time.Duration(cfg.LDAPPoolGetTimeout) * time.Second
+	info := &ServerInfo{}
+
+	rootDSE, err := readEntry(ctx, connPool, timeout, "", []string{"namingContexts", "vendorVersion"})
+	if err != nil {
+		return nil, fmt.Errorf("error reading root DSE: %w", err)
+	}
+	if rootDSE != nil {
+		info.Suffixes = rootDSE.GetEqualFoldAttributeValues("namingContexts")
+		info.Version = rootDSE.GetEqualFoldAttributeValue("vendorVersion")
+	}
+
+	monitor, err := readEntry(ctx, connPool, timeout, "cn=monitor", []string{"version"})
+	if err == nil && monitor != nil && monitor.GetAttributeValue("version") != "" {
+		info.Version = monitor.GetAttributeValue("version")
+	}
+
+	inspectCfg := *cfg
+	inspectCfg.CollectorsDefault = "all"
+	inspectCfg.DSBackendType = ""
+	inspectCfg.DSBackendDBs = nil
+
+	dsCollector := collectors.NewDSCollector(collectors.DSCollectorConfig{})
+	registerGeneralCollectors(&inspectCfg, dsCollector, connPool, timeout)
+
+	discoverer := NewDiscoverer(&inspectCfg, connPool, dsCollector)
+	err = discoverer.Discover(ctx)
+	if err != nil {
+		info.Problems = append(info.Problems, err)
+	}
+	info.BackendType, info.Backends = discoverer.Backend()
+
+	entries := dsCollector.Entries()
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		access := EntryAccess{Collector: name, DN: entries[name], State: EntryReadable}
+
+		entry, err := readEntry(ctx, connPool, timeout, access.DN, []string{"1.1"})
+		switch {
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+			access.State = EntryNotPresent
+		case ldap.IsErrorAnyOf(err, ldap.LDAPResultInsufficientAccessRights, ldap.LDAPResultInappropriateAuthentication):
+			access.State, access.Err = EntryNoAccess, err
+		case err != nil:
+			access.State, access.Err = EntryError, err
+		case entry == nil:
+			// 389-ds hides the entries the bind DN is not allowed to read
+			access.State = EntryNoAccess
+		}
+		info.Entries = append(info.Entries, access)
+
+		if access.State == EntryNoAccess {
+			info.Problems = append(info.Problems, fmt.Errorf(
+				"collector %s: %s is not readable by %s",
+				name,
+				access.DN,
+				cfg.LDAPBindDN,
+			))
+		}
+	}
+
+	return info, nil
+}
+
+// readEntry reads the attributes of a single entry with a base-object search.
+// It returns a nil entry if the search found nothing.
+func readEntry(
+	ctx context.Context,
+	connPool *expldap.Pool,
+	timeout time.Duration,
+	dn string,
+	attributes []string,
+) (*ldap.Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := connPool.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
+	}
+	defer conn.Close()
+
+	req := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		1,
+		0,
+		false,
+		"(objectClass=*)",
+		attributes,
+		nil,
+	)
+	result, err := conn.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	return result.Entries[0], nil
+}
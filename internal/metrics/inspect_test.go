@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/config"
+	expldap "389-ds-exporter/internal/ldap"
+)
+
+// fakeServer is an LDAP connection serving a fixed set of entries
+// and the backend instances below cn=ldbm database,cn=plugins,cn=config.
+type fakeServer struct {
+	entries map[string]map[string][]string
+	denied  map[string]bool
+}
+
+func (f *fakeServer) Bind(_ expldap.AuthConfig) error { return nil }
+func (f *fakeServer) Unbind() error                   { return nil }
+func (f *fakeServer) Close() error                    { return nil }
+
+func (f *fakeServer) Search(_ context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result := &ldap.SearchResult{}
+	if req.Scope == ldap.ScopeSingleLevel {
+		for _, instance := range []string{"userRoot", "changelog"} {
+			entry := ldap.NewEntry("cn="+instance+","+req.BaseDN, map[string][]string{"cn": {instance}})
+			result.Entries = append(result.Entries, entry)
+		}
+		return result, nil
+	}
+	if f.denied[req.BaseDN] {
+		return result, nil
+	}
+	attrs, ok := f.entries[req.BaseDN]
+	if !ok {
+		return nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	}
+	result.Entries = append(result.Entries, ldap.NewEntry(req.BaseDN, attrs))
+	return result, nil
+}
+
+func TestInspect(t *testing.T) {
+	server := &fakeServer{
+		entries: map[string]map[string][]string{
+			"":                   {"namingContexts": {"dc=example,dc=com"}, "vendorVersion": {"389-Directory/2.4.5"}},
+			"cn=monitor":         {"version": {"389-Directory/2.4.5 B2024.017.0000"}},
+			"cn=snmp,cn=monitor": {},
+			"cn=config,cn=ldbm database,cn=plugins,cn=config":               {"nsslapd-backend-implement": {"mdb"}},
+			"cn=monitor,cn=ldbm database,cn=plugins,cn=config":              {},
+			"cn=database,cn=monitor,cn=ldbm database,cn=plugins,cn=config":  {},
+			"cn=monitor,cn=userRoot,cn=ldbm database,cn=plugins,cn=config":  {},
+			"cn=monitor,cn=changelog,cn=ldbm database,cn=plugins,cn=config": {},
+		},
+		denied: map[string]bool{"cn=snmp,cn=monitor": true},
+	}
+	pool := expldap.NewLDAPPool(expldap.PoolConfig{
+		ConnFactory:    func(_ *expldap.AuthConfig) (expldap.Conn, error) { return server, nil },
+		MaxConnections: 1,
+	})
+	t.Cleanup(func() { _ = pool.Close() })
+
+	cfg := &config.ExporterConfig{
+		LDAPPoolGetTimeout:      1,
+		LDAPBindDN:              "cn=exporter",
+		DSBackendType:           config.BackendBDB,
+		DSNumSubordinateRecords: []string{"ou=missing,dc=example,dc=com"},
+	}
+	info, err := Inspect(context.Background(), cfg, pool)
+	require.NoError(t, err)
+
+	require.Equal(t, "389-Directory/2.4.5 B2024.017.0000", info.Version)
+	require.Equal(t, config.BackendMDB, info.BackendType, "The backend type should be detected, not configured")
+	require.Equal(t, []string{"userRoot", "changelog"}, info.Backends)
+	require.Equal(t, []string{"dc=example,dc=com"}, info.Suffixes)
+
+	states := make(map[string]string)
+	for _, entry := range info.Entries {
+		states[entry.Collector] = entry.State
+	}
+	require.Equal(t, map[string]string{
+		"server":                  EntryReadable,
+		"snmp-server":             EntryNoAccess,
+		"ndn-cache":               EntryReadable,
+		"lmdb-internal":           EntryReadable,
+		"ldbm-instance_userRoot":  EntryReadable,
+		"ldbm-instance_changelog": EntryReadable,
+		"numsubordinates_ou=missing,dc=example,dc=com": EntryNotPresent,
+	}, states)
+
+	require.Len(t, info.Problems, 1)
+	require.ErrorContains(t, info.Problems[0], "cn=snmp,cn=monitor is not readable by cn=exporter")
+}
@@ -26,6 +26,23 @@ func standardCollectors() []string {
 	}
 }
 
+// collectorEnabled reports whether the collector is enabled in the configuration.
+// An instanced collector, such as ldbm-instance_userRoot, is also enabled by the name of its family.
+func collectorEnabled(cfg *config.ExporterConfig, name string) bool {
+	family := config.CollectorFamily(name)
+	enabled := slices.Contains(cfg.CollectorsEnabled, name) || slices.Contains(cfg.CollectorsEnabled, family)
+
+	switch cfg.CollectorsDefault {
+	case "all":
+		return true
+	case "none":
+		return enabled
+	case "standard":
+		return enabled || slices.Contains(standardCollectors(), family)
+	}
+	return false
+}
+
 // registerCollectorIfEnabled registers the collector if it is enabled in the configuration.
 func registerCollectorIfEnabled(
 	dsCollector *collectors.DSCollector,
@@ -33,17 +50,7 @@ func registerCollectorIfEnabled(
 	cfg *config.ExporterConfig,
 	collector func() collectors.InternalCollector,
 ) {
-	collectorEnabled := false
-	switch cfg.CollectorsDefault {
-	case "all":
-		collectorEnabled = true
-	case "none":
-		collectorEnabled = slices.Contains(cfg.CollectorsEnabled, name)
-	case "standard":
-		collectorEnabled = slices.Contains(cfg.CollectorsEnabled, name) || slices.Contains(standardCollectors(), name)
-	}
-
-	if collectorEnabled {
+	if collectorEnabled(cfg, name) {
 		slog.Debug("Registering collector", "collector", name)
 		dsCollector.Register(name, collector())
 	}
@@ -60,6 +67,14 @@ func registerGeneralCollectors(
 		return collectors.NewPoolCollector("exporter_pool", connPool, prometheus.Labels{})
 	})
 
+	registerCollectorIfEnabled(dsCollector, "rootdse", cfg, func() collectors.InternalCollector {
+		return collectors.NewRootDSECollector(connPool, prometheus.Labels{}, connPoolTimeout)
+	})
+
+	registerCollectorIfEnabled(dsCollector, "usn", cfg, func() collectors.InternalCollector {
+		return collectors.NewUsnCollector(connPool, prometheus.Labels{}, connPoolTimeout)
+	})
+
 	registerCollectorIfEnabled(dsCollector, "server", cfg, func() collectors.InternalCollector {
 		return collectors.NewLdapEntryCollector(
 			"server",
@@ -110,12 +125,12 @@ func registerGeneralCollectors(
 
 // determineBackendInstances determines the list of backends
 // to use based on the configuration and information in the LDAP directory.
-func determineBackendInstances(cfg *config.ExporterConfig,
+func determineBackendInstances(ctx context.Context, cfg *config.ExporterConfig,
 	pool *expldap.Pool, timeout time.Duration) ([]string, error) {
 
 	if len(cfg.DSBackendDBs) == 0 {
 		slog.Debug("Backend instances not specified, detecting automatically")
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		ldapConn, err := pool.Conn(ctx)
 		if err != nil {
@@ -124,7 +139,7 @@ func determineBackendInstances(cfg *config.ExporterConfig,
 
 		defer ldapConn.Close()
 
-		detectedInstances, err := expldap.GetLdapBackendInstances(ldapConn)
+		detectedInstances, err := expldap.GetLdapBackendInstances(ctx, ldapConn)
 		if err != nil {
 			return nil, fmt.Errorf("backend instances detection error: %w", err)
 		}
@@ -140,11 +155,11 @@ func determineBackendInstances(cfg *config.ExporterConfig,
 
 // determineBackendType determines backend type
 // to use based on the configuration and information in the LDAP directory.
-func determineBackendType(cfg *config.ExporterConfig,
+func determineBackendType(ctx context.Context, cfg *config.ExporterConfig,
 	pool *expldap.Pool, timeout time.Duration) (string, error) {
 
 	if cfg.DSBackendType == "" {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		ldapConn, err := pool.Conn(ctx)
 		if err != nil {
@@ -155,7 +170,7 @@ func determineBackendType(cfg *config.ExporterConfig,
 
 		slog.Debug("Backend type not specified, detecting automatically")
 
-		detectedType, err := expldap.GetLdapBackendType(ldapConn)
+		detectedType, err := expldap.GetLdapBackendType(ctx, ldapConn)
 		if err != nil {
 			return "", fmt.Errorf("backend type detection error: %w", err)
 		}
@@ -169,99 +184,54 @@ func determineBackendType(cfg *config.ExporterConfig,
 	return cfg.DSBackendType, nil
 }
 
-// SetupPrometheusMetrics creates *prometheus.Registry, adds the required metrics and returns it.
+// SetupPrometheusMetrics creates *prometheus.Registry, adds the required metrics and returns it
+// together with the DSCollector and the Discoverer of the backend collectors.
+// The DSCollector is not registered in the registry, so that each scrape can bind it
+// to its own context with DSCollector.Gatherer.
 func SetupPrometheusMetrics(
 	cfg *config.ExporterConfig,
 	connPool *expldap.Pool,
-) *prometheus.Registry {
+) (*prometheus.Registry, *collectors.DSCollector, *Discoverer) {
 
 	slog.Info("Creating collectors...")
 	defer slog.Info("Collectors created")
 	dsMetricsRegistry := prometheus.NewRegistry()
 
-	dsCollector := collectors.NewDSCollector()
 	poolGetTimeout := time.Duration(cfg.LDAPPoolGetTimeout) * time.Second
+	dsCollectorConfig := collectors.DSCollectorConfig{
+		MinInterval:        time.Duration(cfg.CollectorsMinInterval) * time.Second,
+		BackgroundInterval: time.Duration(cfg.CollectorsBackgroundInterval) * time.Second,
+		StaleWindow:        time.Duration(cfg.CollectorsStaleWindow) * time.Second,
+	}
+
+	if cfg.CollectorsSubtreeSearch {
+		// All monitoring entries read by the collectors are located under these two subtrees
+		dsCollectorConfig.Subtree = collectors.NewSubtreeSearcher(
+			connPool,
+			[]string{"cn=monitor", "cn=ldbm database,cn=plugins,cn=config"},
+			poolGetTimeout,
+		)
+	}
+
+	dsCollector := collectors.NewDSCollector(dsCollectorConfig)
 
 	registerGeneralCollectors(cfg, dsCollector, connPool, poolGetTimeout)
 
 	/*
 		Since 389-ds has a different set of monitoring metrics for different backends (Berkley DB and LMDB),
-		at the initialization stage we select the metrics that correspond to the selected backend
+		the discoverer registers the collectors that correspond to the detected backend
+		and keeps them up to date at runtime
 	*/
 
-	backendType, err := determineBackendType(cfg, connPool, poolGetTimeout)
-
-	if err != nil {
-		slog.Error("Error detecting backend type", "err", err)
-	} else {
-		switch backendType {
-		case config.BackendBDB:
-
-			slog.Info("Berkeley DB backend implementation detected")
-
-			registerCollectorIfEnabled(dsCollector, "bdb-caches", cfg, func() collectors.InternalCollector {
-				return collectors.NewLdapEntryCollector(
-					"bdb",
-					connPool,
-					"cn=monitor,cn=ldbm database,cn=plugins,cn=config",
-					GetLdapBDBServerCacheMetrics(),
-					prometheus.Labels{},
-					poolGetTimeout,
-				)
-			})
-
-			registerCollectorIfEnabled(dsCollector, "bdb-internal", cfg, func() collectors.InternalCollector {
-				return collectors.NewLdapEntryCollector(
-					"bdb",
-					connPool,
-					"cn=database,cn=monitor,cn=ldbm database,cn=plugins,cn=config",
-					GetLdapBDBDatabaseLDBM(),
-					prometheus.Labels{},
-					poolGetTimeout,
-				)
-			})
-		case config.BackendMDB:
-			slog.Info("LMDB backend implementation detected")
-
-			registerCollectorIfEnabled(dsCollector, "lmdb-internal", cfg, func() collectors.InternalCollector {
-				return collectors.NewLdapEntryCollector(
-					"lmdb",
-					connPool,
-					"cn=database,cn=monitor,cn=ldbm database,cn=plugins,cn=config",
-					GetLdapMDBDatabaseLDBM(),
-					prometheus.Labels{},
-					poolGetTimeout,
-				)
-			})
-		default:
-			slog.Warn(
-				"An unknown backend implementation type was detected. Backend metrics will not be collected",
-				"backend",
-				backendType,
-			)
-		}
-	}
-
-	detectedBackendInstances, err := determineBackendInstances(cfg, connPool, poolGetTimeout)
+	discoverer := NewDiscoverer(cfg, connPool, dsCollector)
+	err := discoverer.Discover(context.Background())
 	if err != nil {
-		slog.Error("Error detecting backend instances", "err", err)
-	} else {
-		for i := range detectedBackendInstances {
-			registerCollectorIfEnabled(dsCollector, "ldbm-instance_"+detectedBackendInstances[i], cfg,
-				func() collectors.InternalCollector {
-					return collectors.NewLdapEntryCollector(
-						"ldbm_instance",
-						connPool,
-						"cn=monitor,cn="+detectedBackendInstances[i]+",cn=ldbm database,cn=plugins,cn=config",
-						GetLdapBackendCaches(),
-						prometheus.Labels{"database": detectedBackendInstances[i]},
-						poolGetTimeout,
-					)
-				})
-		}
+		slog.Error("Error discovering backend", "err", err)
 	}
+	dsCollector.OnFailure(func(_ string, _ error) { discoverer.Trigger() })
+	dsMetricsRegistry.MustRegister(discoverer)
+	dsMetricsRegistry.MustRegister(collectors.NewLDAPStateCollector(connPool))
+	dsMetricsRegistry.MustRegister(collectors.NewLDAPSearchCollector(connPool))
 
-	dsMetricsRegistry.MustRegister(dsCollector)
-
-	return dsMetricsRegistry
+	return dsMetricsRegistry, dsCollector, discoverer
 }
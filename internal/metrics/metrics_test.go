@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"389-ds-exporter/internal/config"
+)
+
+func TestCollectorEnabledByFamily(t *testing.T) {
+	cfg := &config.ExporterConfig{CollectorsDefault: "none", CollectorsEnabled: []string{"ldbm-instance", "server"}}
+	require.True(t, collectorEnabled(cfg, "ldbm-instance_userRoot"))
+	require.True(t, collectorEnabled(cfg, "server"))
+	require.False(t, collectorEnabled(cfg, "numsubordinates_ou=people,dc=example,dc=com"))
+
+	cfg = &config.ExporterConfig{CollectorsDefault: "standard"}
+	require.True(t, collectorEnabled(cfg, "numsubordinates_ou=people,dc=example,dc=com"),
+		"Instanced collectors of a standard family should be enabled")
+	require.False(t, collectorEnabled(cfg, "bdb-internal"))
+	require.False(t, collectorEnabled(cfg, "rootdse"), "The rootdse collector should be opt-in")
+}
@@ -0,0 +1,149 @@
+package otlp
+
+import (
+	"math"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Metric and label carrying the 389 Directory Server version.
+const (
+	serverVersionMetric = "ds_server_version"
+	serverVersionLabel  = "version"
+)
+
+// convertFamilies converts the gathered metric families to OTLP metrics.
+// Cumulative sums and histograms start at startTime, data points without a timestamp get now.
+// Summaries are not converted.
+func convertFamilies(families []*dto.MetricFamily, startTime time.Time, now time.Time) []metricdata.Metrics {
+	converted := make([]metricdata.Metrics, 0, len(families))
+	for _, family := range families {
+		var data metricdata.Aggregation
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			data = convertCounter(family, startTime, now)
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			data = convertGauge(family, now)
+		case dto.MetricType_HISTOGRAM:
+			data = convertHistogram(family, startTime, now)
+		default:
+			continue
+		}
+
+		converted = append(converted, metricdata.Metrics{
+			Name:        family.GetName(),
+			Description: family.GetHelp(),
+			Data:        data,
+		})
+	}
+	return converted
+}
+
+// convertCounter converts a counter family to a cumulative monotonic sum.
+func convertCounter(family *dto.MetricFamily, startTime time.Time, now time.Time) metricdata.Sum[float64] {
+	points := make([]metricdata.DataPoint[float64], 0, len(family.GetMetric()))
+	for _, metric := range family.GetMetric() {
+		points = append(points, metricdata.DataPoint[float64]{
+			Attributes: attributes(metric),
+			StartTime:  startTime,
+			Time:       timestamp(metric, now),
+			Value:      metric.GetCounter().GetValue(),
+		})
+	}
+	return metricdata.Sum[float64]{
+		DataPoints:  points,
+		Temporality: metricdata.CumulativeTemporality,
+		IsMonotonic: true,
+	}
+}
+
+// convertGauge converts a gauge or untyped family to a gauge.
+func convertGauge(family *dto.MetricFamily, now time.Time) metricdata.Gauge[float64] {
+	points := make([]metricdata.DataPoint[float64], 0, len(family.GetMetric()))
+	for _, metric := range family.GetMetric() {
+		value := metric.GetGauge().GetValue()
+		if family.GetType() == dto.MetricType_UNTYPED {
+			value = metric.GetUntyped().GetValue()
+		}
+		points = append(points, metricdata.DataPoint[float64]{
+			Attributes: attributes(metric),
+			Time:       timestamp(metric, now),
+			Value:      value,
+		})
+	}
+	return metricdata.Gauge[float64]{DataPoints: points}
+}
+
+// convertHistogram converts a histogram family to a cumulative explicit bucket histogram.
+// Prometheus buckets are cumulative while OTLP bucket counts are not, the +Inf bucket is implicit in Prometheus.
+func convertHistogram(family *dto.MetricFamily, startTime time.Time, now time.Time) metricdata.Histogram[float64] {
+	points := make([]metricdata.HistogramDataPoint[float64], 0, len(family.GetMetric()))
+	for _, metric := range family.GetMetric() {
+		histogram := metric.GetHistogram()
+
+		bounds := make([]float64, 0, len(histogram.GetBucket()))
+		counts := make([]uint64, 0, len(histogram.GetBucket())+1)
+		var previous uint64
+		for _, bucket := range histogram.GetBucket() {
+			if math.IsInf(bucket.GetUpperBound(), 1) {
+				continue
+			}
+			bounds = append(bounds, bucket.GetUpperBound())
+			counts = append(counts, bucket.GetCumulativeCount()-previous)
+			previous = bucket.GetCumulativeCount()
+		}
+		counts = append(counts, histogram.GetSampleCount()-previous)
+
+		points = append(points, metricdata.HistogramDataPoint[float64]{
+			Attributes:   attributes(metric),
+			StartTime:    startTime,
+			Time:         timestamp(metric, now),
+			Count:        histogram.GetSampleCount(),
+			Bounds:       bounds,
+			BucketCounts: counts,
+			Sum:          histogram.GetSampleSum(),
+		})
+	}
+	return metricdata.Histogram[float64]{
+		DataPoints:  points,
+		Temporality: metricdata.CumulativeTemporality,
+	}
+}
+
+// attributes converts the labels of the metric to attributes.
+func attributes(metric *dto.Metric) attribute.Set {
+	keyValues := make([]attribute.KeyValue, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		keyValues = append(keyValues, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(keyValues...)
+}
+
+// timestamp returns the timestamp of the metric or now if the metric has none.
+func timestamp(metric *dto.Metric, now time.Time) time.Time {
+	if metric.TimestampMs == nil {
+		return now
+	}
+	return time.UnixMilli(metric.GetTimestampMs())
+}
+
+// serverVersion returns the 389 Directory Server version from the version label of the server collector metric,
+// or an empty string if the server collector is disabled or failed.
+func serverVersion(families []*dto.MetricFamily) string {
+	for _, family := range families {
+		if family.GetName() != serverVersionMetric {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == serverVersionLabel {
+					return label.GetValue()
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func gatherTestRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ds_ops_total", Help: "Operations."}, []string{"op"})
+	counter.WithLabelValues("search").Add(3)
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "ds_server_version", Help: "Version."}, []string{"version"})
+	gauge.WithLabelValues("2.4.5").Set(1)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ds_duration_seconds",
+		Help:    "Duration.",
+		Buckets: []float64{0.1, 1},
+	})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	registry.MustRegister(counter, gauge, histogram)
+	return registry
+}
+
+func TestConvertFamilies(t *testing.T) {
+	families, err := gatherTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	start := time.Unix(100, 0)
+	now := time.Unix(200, 0)
+	converted := convertFamilies(families, start, now)
+	require.Len(t, converted, 3)
+
+	byName := make(map[string]metricdata.Metrics)
+	for _, metric := range converted {
+		byName[metric.Name] = metric
+	}
+
+	sum, ok := byName["ds_ops_total"].Data.(metricdata.Sum[float64])
+	require.True(t, ok, "Counters should be converted to sums")
+	require.True(t, sum.IsMonotonic)
+	require.Equal(t, metricdata.CumulativeTemporality, sum.Temporality)
+	require.Len(t, sum.DataPoints, 1)
+	require.InDelta(t, 3.0, sum.DataPoints[0].Value, 0)
+	require.Equal(t, start, sum.DataPoints[0].StartTime)
+	require.Equal(t, now, sum.DataPoints[0].Time)
+	op, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("op"))
+	require.Equal(t, "search", op.AsString())
+
+	gauge, ok := byName["ds_server_version"].Data.(metricdata.Gauge[float64])
+	require.True(t, ok, "Gauges should be converted to gauges")
+	require.InDelta(t, 1.0, gauge.DataPoints[0].Value, 0)
+	require.Equal(t, "Version.", byName["ds_server_version"].Description)
+
+	histogram, ok := byName["ds_duration_seconds"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok, "Histograms should be converted to histograms")
+	point := histogram.DataPoints[0]
+	require.Equal(t, uint64(3), point.Count)
+	require.Equal(t, []float64{0.1, 1}, point.Bounds)
+	require.Equal(t, []uint64{1, 1, 1}, point.BucketCounts, "Bucket counts should not be cumulative")
+	require.InDelta(t, 5.55, point.Sum, 1e-9)
+
+	require.Equal(t, "2.4.5", serverVersion(families))
+	require.Empty(t, serverVersion(families[:1]))
+}
@@ -0,0 +1,207 @@
+/*
+Package otlp pushes the exporter metrics to an OpenTelemetry collector over OTLP/HTTP or OTLP/gRPC.
+*/
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+
+	"389-ds-exporter/internal/config"
+)
+
+const (
+	scopeName   = "389-ds-exporter"
+	serviceName = "389-ds-exporter"
+
+	// retryInitialInterval and retryMaxInterval bound the exponential backoff between the retries of a failed push.
+	retryInitialInterval = time.Second
+	retryMaxInterval     = 30 * time.Second
+)
+
+// Pusher periodically gathers the exporter metrics and pushes them to an OpenTelemetry collector.
+// Counters are pushed as cumulative monotonic sums starting at the creation of the Pusher,
+// gauges and untyped metrics as gauges and histograms as cumulative explicit bucket histograms.
+type Pusher struct {
+	gather    func(context.Context) prometheus.Gatherer
+	exporter  sdkmetric.Exporter
+	resource  *resource.Resource
+	interval  time.Duration
+	startTime time.Time
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewPusher creates the OTLP exporter for the otlp_metrics_* settings of the configuration.
+// The gather function returns the gatherer of the metrics to push, bound to the context of the push.
+func NewPusher(
+	ctx context.Context,
+	cfg *config.ExporterConfig,
+	version string,
+	gather func(context.Context) prometheus.Gatherer,
+) (*Pusher, error) {
+	interval := time.Duration(cfg.OTLPMetricsInterval) * time.Second
+	exporter, err := newExporter(ctx, cfg, interval)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP metric exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+			semconv.ServiceInstanceID(instanceID()),
+			attribute.StringSlice("ds.server.url", cfg.ServerURLs()),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating metric resource: %w", err)
+	}
+
+	return &Pusher{
+		gather:    gather,
+		exporter:  exporter,
+		resource:  res,
+		interval:  interval,
+		startTime: time.Now(),
+	}, nil
+}
+
+// newExporter creates the OTLP/HTTP or OTLP/gRPC exporter retrying failed pushes with an exponential backoff.
+// A push with its retries is abandoned after the push interval, so that pushes never overlap.
+func newExporter(ctx context.Context, cfg *config.ExporterConfig, timeout time.Duration) (sdkmetric.Exporter, error) {
+	headers := make(map[string]string, len(cfg.OTLPMetricsHeaders))
+	for _, header := range cfg.OTLPMetricsHeaders {
+		name, value, _ := strings.Cut(header, "=")
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	switch cfg.OTLPMetricsProtocol {
+	case config.OTLPProtocolGRPC:
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(cfg.OTLPMetricsEndpoint),
+			otlpmetricgrpc.WithHeaders(headers),
+			otlpmetricgrpc.WithTimeout(timeout),
+			otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+				Enabled:         true,
+				InitialInterval: retryInitialInterval,
+				MaxInterval:     retryMaxInterval,
+				MaxElapsedTime:  timeout,
+			}),
+		)
+	case config.OTLPProtocolHTTP:
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(cfg.OTLPMetricsEndpoint),
+			otlpmetrichttp.WithHeaders(headers),
+			otlpmetrichttp.WithTimeout(timeout),
+			otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+				Enabled:         true,
+				InitialInterval: retryInitialInterval,
+				MaxInterval:     retryMaxInterval,
+				MaxElapsedTime:  timeout,
+			}),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", cfg.OTLPMetricsProtocol)
+	}
+}
+
+// instanceID returns the host name of the exporter, which identifies the instance like the Prometheus instance label.
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// Start starts pushing the metrics every otlp_metrics_interval.
+func (p *Pusher) Start() {
+	if p.stop != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stop = cancel
+	p.wg.Add(1)
+	go p.loop(ctx)
+}
+
+// Stop stops the periodic push, waits for the running push to finish and shuts the OTLP exporter down.
+func (p *Pusher) Stop(ctx context.Context) error {
+	if p.stop != nil {
+		p.stop()
+		p.wg.Wait()
+		p.stop = nil
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+// loop pushes the metrics on schedule until ctx is done.
+func (p *Pusher) loop(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pushContext, cancel := context.WithTimeout(ctx, p.interval)
+		err := p.Push(pushContext)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("OTLP metrics push failed", "err", err)
+		}
+	}
+}
+
+// Push gathers the metrics once and pushes them to the OpenTelemetry collector.
+// Metrics gathered with an error are pushed anyway and the error is logged.
+func (p *Pusher) Push(ctx context.Context) error {
+	families, err := p.gather(ctx).Gather()
+	if err != nil {
+		slog.Warn("Error gathering metrics for the OTLP push", "err", err)
+	}
+
+	now := time.Now()
+	res := p.resource
+	dsVersion := serverVersion(families)
+	if dsVersion != "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String("ds.version", dsVersion)))
+		if err != nil {
+			return fmt.Errorf("error adding the server version to the resource: %w", err)
+		}
+	}
+
+	return p.exporter.Export(ctx, &metricdata.ResourceMetrics{
+		Resource: res,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: scopeName},
+			Metrics: convertFamilies(families, p.startTime, now),
+		}},
+	})
+}
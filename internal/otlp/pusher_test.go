@@ -0,0 +1,127 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"389-ds-exporter/internal/config"
+)
+
+// receiver is an OTLP/HTTP metrics receiver failing the first failures requests.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests int
+	headers  http.Header
+	received []*colmetricpb.ExportMetricsServiceRequest
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	request := &colmetricpb.ExportMetricsServiceRequest{}
+	err = proto.Unmarshal(body, request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.headers = req.Header.Clone()
+	r.received = append(r.received, request)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func newTestPusher(t *testing.T, endpoint string, registry prometheus.Gatherer) *Pusher {
+	t.Helper()
+
+	cfg := &config.ExporterConfig{
+		LDAPServerURL:       "ldap://ds.example.com:389",
+		OTLPMetricsEndpoint: endpoint,
+		OTLPMetricsProtocol: config.OTLPProtocolHTTP,
+		OTLPMetricsInterval: 10,
+		OTLPMetricsHeaders:  []string{"Authorization=Bearer token"},
+	}
+	pusher, err := NewPusher(t.Context(), cfg, "1.2.3", func(context.Context) prometheus.Gatherer {
+		return registry
+	})
+	require.NoError(t, err)
+	return pusher
+}
+
+func TestPush(t *testing.T) {
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	pusher := newTestPusher(t, server.URL+"/v1/metrics", gatherTestRegistry(t))
+	err := pusher.Push(t.Context())
+	require.NoError(t, err, "The push should be retried after a failure")
+	require.NoError(t, pusher.Stop(t.Context()))
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	require.Equal(t, 2, recv.requests)
+	require.Len(t, recv.received, 1)
+	require.Equal(t, "Bearer token", recv.headers.Get("Authorization"))
+
+	resourceMetrics := recv.received[0].GetResourceMetrics()
+	require.Len(t, resourceMetrics, 1)
+
+	attributes := make(map[string]string)
+	for _, keyValue := range resourceMetrics[0].GetResource().GetAttributes() {
+		attributes[keyValue.GetKey()] = keyValue.GetValue().GetStringValue()
+	}
+	require.Equal(t, "389-ds-exporter", attributes["service.name"])
+	require.Equal(t, "1.2.3", attributes["service.version"])
+	require.Equal(t, "2.4.5", attributes["ds.version"])
+	require.NotEmpty(t, attributes["service.instance.id"])
+
+	names := make(map[string]bool)
+	for _, metric := range resourceMetrics[0].GetScopeMetrics()[0].GetMetrics() {
+		names[metric.GetName()] = true
+		if metric.GetName() == "ds_ops_total" {
+			require.True(t, metric.GetSum().GetIsMonotonic())
+			require.InDelta(t, 3.0, metric.GetSum().GetDataPoints()[0].GetAsDouble(), 0)
+		}
+	}
+	require.Equal(t, map[string]bool{"ds_ops_total": true, "ds_server_version": true, "ds_duration_seconds": true}, names)
+}
+
+func TestPusherLoop(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	pusher := newTestPusher(t, server.URL+"/v1/metrics", gatherTestRegistry(t))
+	pusher.interval = 10 * time.Millisecond
+	pusher.Start()
+
+	require.Eventually(t, func() bool {
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		return len(recv.received) >= 2
+	}, 5*time.Second, 10*time.Millisecond, "Metrics should be pushed periodically")
+	require.NoError(t, pusher.Stop(t.Context()))
+}
@@ -0,0 +1,145 @@
+package remotewrite
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the remote-write protobuf messages, see prometheus/prompb/remote.proto and types.proto.
+const (
+	writeRequestTimeseries protowire.Number = 1
+	timeSeriesLabels       protowire.Number = 1
+	timeSeriesSamples      protowire.Number = 2
+	labelName              protowire.Number = 1
+	labelValue             protowire.Number = 2
+	sampleValue            protowire.Number = 1
+	sampleTimestamp        protowire.Number = 2
+)
+
+// Label is a name and value pair of a series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// series is a remote-write time series with a single sample.
+type series struct {
+	labels    []Label // sorted by name
+	value     float64
+	timestamp int64 // milliseconds since the epoch
+}
+
+// buildSeries flattens the gathered metric families into series the way Prometheus stores them:
+// histograms become the _bucket, _sum and _count series and summaries the quantile, _sum and _count series.
+// External labels are added to every series that does not have a label with the same name.
+func buildSeries(families []*dto.MetricFamily, externalLabels []Label, now time.Time) []series {
+	var result []series
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			timestamp := now.UnixMilli()
+			if metric.TimestampMs != nil {
+				timestamp = metric.GetTimestampMs()
+			}
+			add := func(suffix string, value float64, extra ...Label) {
+				result = append(result, series{
+					labels:    seriesLabels(name+suffix, metric, extra, externalLabels),
+					value:     value,
+					timestamp: timestamp,
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				infSeen := false
+				for _, bucket := range histogram.GetBucket() {
+					infSeen = infSeen || math.IsInf(bucket.GetUpperBound(), 1)
+					add("_bucket", float64(bucket.GetCumulativeCount()), Label{"le", formatFloat(bucket.GetUpperBound())})
+				}
+				if !infSeen {
+					add("_bucket", float64(histogram.GetSampleCount()), Label{"le", "+Inf"})
+				}
+				add("_sum", histogram.GetSampleSum())
+				add("_count", float64(histogram.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					add("", quantile.GetValue(), Label{"quantile", formatFloat(quantile.GetQuantile())})
+				}
+				add("_sum", summary.GetSampleSum())
+				add("_count", float64(summary.GetSampleCount()))
+			}
+		}
+	}
+	return result
+}
+
+// seriesLabels returns the sorted labels of a series: the metric name, the metric labels, the extra labels
+// of histogram buckets and summary quantiles, and the external labels missing from the series.
+func seriesLabels(name string, metric *dto.Metric, extra []Label, externalLabels []Label) []Label {
+	labels := make([]Label, 0, len(metric.GetLabel())+len(extra)+len(externalLabels)+1)
+	labels = append(labels, Label{"__name__", name})
+	for _, label := range metric.GetLabel() {
+		labels = append(labels, Label{label.GetName(), label.GetValue()})
+	}
+	labels = append(labels, extra...)
+	for _, external := range externalLabels {
+		if !slices.ContainsFunc(labels, func(label Label) bool { return label.Name == external.Name }) {
+			labels = append(labels, external)
+		}
+	}
+	slices.SortFunc(labels, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+	return labels
+}
+
+// formatFloat formats the bucket bound or quantile like the Prometheus text format does.
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protobuf message.
+func encodeWriteRequest(allSeries []series) []byte {
+	var request []byte
+	for _, s := range allSeries {
+		var timeSeries []byte
+		for _, label := range s.labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, labelName, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Name)
+			encoded = protowire.AppendTag(encoded, labelValue, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Value)
+
+			timeSeries = protowire.AppendTag(timeSeries, timeSeriesLabels, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, encoded)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+
+		timeSeries = protowire.AppendTag(timeSeries, timeSeriesSamples, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, sample)
+
+		request = protowire.AppendTag(request, writeRequestTimeseries, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeSeries)
+	}
+	return request
+}
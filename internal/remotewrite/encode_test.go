@@ -0,0 +1,68 @@
+package remotewrite
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSeries(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "ds_conns", Help: "Connections."}, []string{"site"})
+	gauge.WithLabelValues("hq").Set(7)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ds_duration_seconds",
+		Help:    "Duration.",
+		Buckets: []float64{0.1, 1},
+	})
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	registry.MustRegister(gauge, histogram)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1700000000000)
+	allSeries := buildSeries(families, []Label{{"site", "branch-1"}, {"job", "ds"}}, now)
+
+	values := make(map[string]float64)
+	for _, s := range allSeries {
+		require.Equal(t, now.UnixMilli(), s.timestamp)
+		require.True(t, slices.IsSorted(labelNames(s.labels)), "Labels should be sorted by name")
+		values[seriesKey(s.labels)] = s.value
+	}
+	require.Equal(t, map[string]float64{
+		`ds_conns{job="ds",site="hq"}`:                                   7,
+		`ds_duration_seconds_bucket{job="ds",le="0.1",site="branch-1"}`:  0,
+		`ds_duration_seconds_bucket{job="ds",le="1",site="branch-1"}`:    1,
+		`ds_duration_seconds_bucket{job="ds",le="+Inf",site="branch-1"}`: 2,
+		`ds_duration_seconds_sum{job="ds",site="branch-1"}`:              5.5,
+		`ds_duration_seconds_count{job="ds",site="branch-1"}`:            2,
+	}, values, "Metric labels should take precedence over the external labels")
+}
+
+func labelNames(labels []Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func seriesKey(labels []Label) string {
+	var name, rest string
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			name = label.Value
+			continue
+		}
+		if rest != "" {
+			rest += ","
+		}
+		rest += label.Name + `="` + label.Value + `"`
+	}
+	return name + "{" + rest + "}"
+}
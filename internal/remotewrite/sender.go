@@ -0,0 +1,249 @@
+/*
+Package remotewrite sends the exporter metrics to a Prometheus remote-write endpoint.
+*/
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"389-ds-exporter/internal/config"
+)
+
+const (
+	defaultJob = "389-ds-exporter"
+
+	// maxErrorBody is the number of bytes of the response body included in the send errors.
+	maxErrorBody = 256
+)
+
+// errRecoverable indicates that the request may succeed later and the batch is kept in the queue.
+var errRecoverable = errors.New("recoverable remote-write error")
+
+// Sender periodically gathers the exporter metrics and sends them to a Prometheus remote-write endpoint
+// as snappy-compressed protobuf WriteRequests.
+// Batches that fail with a network error, 429 or 5xx are kept in a bounded in-memory queue
+// and resent in order before the next batch; the oldest batch is dropped when the queue is full.
+// The metrics are gathered within the interval and every request has its own timeout of the interval,
+// so resending a full queue may take up to queueSize intervals, during which the scheduled sends are skipped.
+type Sender struct {
+	url            string
+	username       string
+	password       string
+	bearerToken    string
+	userAgent      string
+	externalLabels []Label
+	interval       time.Duration
+	timeout        time.Duration // timeout of a single request
+	queueSize      int
+	client         *http.Client
+	gather         func(context.Context) prometheus.Gatherer
+
+	mu    sync.Mutex
+	queue [][]byte // compressed requests waiting to be sent, oldest first
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewSender creates the Sender for the remote_write_* settings of the configuration.
+// The gather function returns the gatherer of the metrics to send, bound to the context of the send.
+// Unless they are set in remote_write_external_labels, the job label is 389-ds-exporter
+// and the instance label is the host name of the exporter.
+func NewSender(
+	cfg *config.ExporterConfig,
+	version string,
+	gather func(context.Context) prometheus.Gatherer,
+) *Sender {
+	interval := time.Duration(cfg.RemoteWriteInterval) * time.Second
+	return &Sender{
+		url:            cfg.RemoteWriteURL,
+		username:       cfg.RemoteWriteUsername,
+		password:       cfg.RemoteWritePassword,
+		bearerToken:    cfg.RemoteWriteBearerToken,
+		userAgent:      "389-ds-exporter/" + version,
+		externalLabels: externalLabels(cfg.RemoteWriteExternalLabels),
+		interval:       interval,
+		timeout:        interval,
+		queueSize:      cfg.RemoteWriteQueueSize,
+		client:         &http.Client{},
+		gather:         gather,
+	}
+}
+
+// externalLabels parses the "name=value" external labels and adds the default job and instance labels.
+func externalLabels(entries []string) []Label {
+	labels := make([]Label, 0, len(entries)+2)
+	for _, entry := range entries {
+		name, value, _ := strings.Cut(entry, "=")
+		labels = append(labels, Label{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	has := func(name string) bool {
+		return slices.ContainsFunc(labels, func(label Label) bool { return label.Name == name })
+	}
+	if !has("job") {
+		labels = append(labels, Label{Name: "job", Value: defaultJob})
+	}
+	if !has("instance") {
+		hostname, err := os.Hostname()
+		if err == nil {
+			labels = append(labels, Label{Name: "instance", Value: hostname})
+		}
+	}
+	return labels
+}
+
+// Start starts sending the metrics every remote_write_interval.
+func (s *Sender) Start() {
+	if s.stop != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Stop stops the periodic send and waits for the running send to finish.
+// Batches left in the queue are discarded.
+func (s *Sender) Stop() {
+	if s.stop == nil {
+		return
+	}
+	s.stop()
+	s.wg.Wait()
+	s.stop = nil
+}
+
+// loop sends the metrics on schedule until ctx is done.
+func (s *Sender) loop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Send(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("Remote write failed", "err", err, "queued_batches", s.QueueLength())
+		}
+	}
+}
+
+// Send gathers the metrics once, appends them to the queue and sends the queued batches in order.
+// The metrics are gathered within the interval and every batch is sent within its own timeout.
+// Metrics gathered with an error are sent anyway and the error is logged.
+func (s *Sender) Send(ctx context.Context) error {
+	gatherContext, cancel := context.WithTimeout(ctx, s.interval)
+	families, err := s.gather(gatherContext).Gather()
+	cancel()
+	if err != nil {
+		slog.Warn("Error gathering metrics for the remote write", "err", err)
+	}
+	request := encodeWriteRequest(buildSeries(families, s.externalLabels, time.Now()))
+	s.enqueue(snappy.Encode(nil, request))
+
+	return s.flush(ctx)
+}
+
+// QueueLength returns the number of batches waiting to be sent.
+func (s *Sender) QueueLength() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// enqueue appends the batch to the queue, dropping the oldest batch if the queue is full.
+func (s *Sender) enqueue(batch []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= s.queueSize {
+		slog.Warn("Remote-write queue is full, dropping the oldest batch", "queue_size", s.queueSize)
+		s.queue = s.queue[1:]
+	}
+	s.queue = append(s.queue, batch)
+}
+
+// flush sends the queued batches oldest first. It stops at the first recoverable error, so that the failed
+// batch and the following ones are retried with the next send. Batches rejected by the endpoint are dropped.
+func (s *Sender) flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for len(s.queue) > 0 {
+		err := s.post(ctx, s.queue[0])
+		if errors.Is(err, errRecoverable) {
+			return errors.Join(append(errs, err)...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("batch dropped: %w", err))
+		}
+		s.queue = s.queue[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// post sends one compressed WriteRequest within the request timeout.
+// Network errors, timeouts, 429 and 5xx responses are recoverable.
+func (s *Sender) post(ctx context.Context, batch []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	switch {
+	case s.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRecoverable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %w", errRecoverable, err)
+	}
+	return err
+}
@@ -0,0 +1,309 @@
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"389-ds-exporter/internal/config"
+)
+
+// decodeWriteRequest decodes a WriteRequest into "name{label="value",...}" series keys and sample values.
+func decodeWriteRequest(t *testing.T, data []byte) map[string]float64 {
+	t.Helper()
+
+	fields := func(message []byte, handle func(number protowire.Number, value []byte, fixed uint64)) {
+		for len(message) > 0 {
+			number, wireType, n := protowire.ConsumeTag(message)
+			require.GreaterOrEqual(t, n, 0)
+			message = message[n:]
+			switch wireType {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeBytes(message)
+				require.GreaterOrEqual(t, n, 0)
+				handle(number, value, 0)
+				message = message[n:]
+			case protowire.Fixed64Type:
+				value, n := protowire.ConsumeFixed64(message)
+				require.GreaterOrEqual(t, n, 0)
+				handle(number, nil, value)
+				message = message[n:]
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(message)
+				require.GreaterOrEqual(t, n, 0)
+				handle(number, nil, value)
+				message = message[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", wireType)
+			}
+		}
+	}
+
+	result := make(map[string]float64)
+	fields(data, func(_ protowire.Number, timeSeries []byte, _ uint64) {
+		var (
+			name   string
+			labels []string
+			value  float64
+		)
+		fields(timeSeries, func(number protowire.Number, message []byte, _ uint64) {
+			switch number {
+			case timeSeriesLabels:
+				var label Label
+				fields(message, func(number protowire.Number, value []byte, _ uint64) {
+					if number == labelName {
+						label.Name = string(value)
+					} else {
+						label.Value = string(value)
+					}
+				})
+				if label.Name == "__name__" {
+					name = label.Value
+				} else {
+					labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+				}
+			case timeSeriesSamples:
+				fields(message, func(number protowire.Number, _ []byte, fixed uint64) {
+					if number == sampleValue {
+						value = math.Float64frombits(fixed)
+					}
+				})
+			}
+		})
+		sort.Strings(labels)
+		result[name+"{"+strings.Join(labels, ",")+"}"] = value
+	})
+	return result
+}
+
+// receiver is a remote-write receiver answering with the status codes in order, then with 204.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	received []map[string]float64
+	t        *testing.T
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	compressed, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+	data, err := snappy.Decode(nil, compressed)
+	require.NoError(r.t, err)
+	r.received = append(r.received, decodeWriteRequest(r.t, data))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestSender(url string, gatherer prometheus.Gatherer, modify func(*config.ExporterConfig)) *Sender {
+	cfg := &config.ExporterConfig{
+		RemoteWriteURL:            url,
+		RemoteWriteInterval:       10,
+		RemoteWriteQueueSize:      2,
+		RemoteWriteExternalLabels: []string{"site=branch-1", "instance=ds1.example.com"},
+	}
+	if modify != nil {
+		modify(cfg)
+	}
+	return NewSender(cfg, "1.2.3", func(context.Context) prometheus.Gatherer {
+		return gatherer
+	})
+}
+
+func newTestRegistry() (*prometheus.Registry, prometheus.Counter) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "ds_ops_total", Help: "Operations."})
+	registry.MustRegister(counter)
+	return registry, counter
+}
+
+func TestSend(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, counter := newTestRegistry()
+	counter.Add(5)
+	sender := newTestSender(server.URL, registry, func(cfg *config.ExporterConfig) {
+		cfg.RemoteWriteUsername = "exporter"
+		cfg.RemoteWritePassword = "secret"
+	})
+
+	require.NoError(t, sender.Send(t.Context()))
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	require.Len(t, recv.received, 1)
+	require.Equal(t, map[string]float64{
+		`ds_ops_total{instance="ds1.example.com",job="389-ds-exporter",site="branch-1"}`: 5,
+	}, recv.received[0])
+
+	req := recv.requests[0]
+	require.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+	require.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+	require.Equal(t, "0.1.0", req.Header.Get("X-Prometheus-Remote-Write-Version"))
+	require.Equal(t, "389-ds-exporter/1.2.3", req.Header.Get("User-Agent"))
+	username, password, ok := req.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "exporter", username)
+	require.Equal(t, "secret", password)
+}
+
+func TestSendBearerToken(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(server.URL, registry, func(cfg *config.ExporterConfig) {
+		cfg.RemoteWriteBearerToken = "token"
+	})
+
+	require.NoError(t, sender.Send(t.Context()))
+	require.Equal(t, "Bearer token", recv.requests[0].Header.Get("Authorization"))
+}
+
+func TestSendRetryQueue(t *testing.T) {
+	recv := &receiver{t: t, statuses: []int{
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+	}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, counter := newTestRegistry()
+	sender := newTestSender(server.URL, registry, nil)
+
+	for i := range 3 {
+		counter.Inc()
+		err := sender.Send(t.Context())
+		require.Error(t, err, "Send %d should fail", i)
+		require.LessOrEqual(t, sender.QueueLength(), 2, "The queue should be bounded")
+	}
+	require.Equal(t, 2, sender.QueueLength())
+
+	counter.Inc()
+	require.NoError(t, sender.Send(t.Context()))
+	require.Equal(t, 0, sender.QueueLength())
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	values := make([]float64, 0, len(recv.received))
+	for _, received := range recv.received {
+		for _, value := range received {
+			values = append(values, value)
+		}
+	}
+	require.Equal(t, []float64{3, 4}, values, "Queued batches should be sent oldest first, the oldest ones dropped")
+}
+
+func TestSendDropsRejectedBatch(t *testing.T) {
+	recv := &receiver{t: t, statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(server.URL, registry, nil)
+
+	err := sender.Send(t.Context())
+	require.ErrorContains(t, err, "batch dropped")
+	require.NotErrorIs(t, err, errRecoverable)
+	require.Equal(t, 0, sender.QueueLength(), "Batches rejected by the endpoint should not be retried")
+}
+
+func TestSendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(url, registry, nil)
+
+	err := sender.Send(t.Context())
+	require.ErrorIs(t, err, errRecoverable)
+	require.Equal(t, 1, sender.QueueLength())
+}
+
+func TestSenderLoop(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(server.URL, registry, nil)
+	sender.interval = 10 * time.Millisecond
+	sender.Start()
+
+	require.Eventually(t, func() bool {
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		return len(recv.received) >= 2
+	}, 5*time.Second, 10*time.Millisecond, "Metrics should be sent periodically")
+	sender.Stop()
+}
+
+func TestSendRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(server.URL, registry, nil)
+	sender.timeout = 50 * time.Millisecond
+
+	err := sender.Send(t.Context())
+	require.ErrorIs(t, err, errRecoverable, "A hanging request should time out")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, sender.QueueLength(), "The timed out batch should be kept in the queue")
+}
+
+func TestSendAfterSlowGather(t *testing.T) {
+	recv := &receiver{t: t}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	registry, _ := newTestRegistry()
+	sender := newTestSender(server.URL, registry, nil)
+	sender.interval = 50 * time.Millisecond
+	sender.timeout = 50 * time.Millisecond
+	sender.gather = func(ctx context.Context) prometheus.Gatherer {
+		// the gather uses up its whole deadline
+		<-ctx.Done()
+		return registry
+	}
+
+	require.NoError(t, sender.Send(t.Context()), "The request should not share the deadline of the gather")
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	require.Len(t, recv.received, 1)
+}
@@ -0,0 +1,142 @@
+/*
+Package tracing configures the OpenTelemetry tracing of the exporter with the standard OTEL_* environment variables.
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "389-ds-exporter"
+	serviceName = "389-ds-exporter"
+)
+
+// OTLP protocols selected by OTEL_EXPORTER_OTLP_TRACES_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// Tracer returns the tracer of the exporter.
+// Spans are recorded only after Setup enabled tracing, otherwise they are no-ops.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Enabled reports whether tracing is requested by the environment: OTEL_TRACES_EXPORTER is otlp
+// or an OTLP endpoint is set, and the SDK is not disabled with OTEL_SDK_DISABLED.
+func Enabled() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED"))
+	if disabled {
+		return false
+	}
+
+	switch os.Getenv("OTEL_TRACES_EXPORTER") {
+	case "otlp":
+		return true
+	case "":
+		return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	default:
+		return false
+	}
+}
+
+// Protocol returns the OTLP protocol of the traces exporter, http/protobuf by default.
+func Protocol() string {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol == "" {
+		return ProtocolHTTPProtobuf
+	}
+	return protocol
+}
+
+// Setup installs the global tracer provider exporting the spans over OTLP if Enabled.
+// The exporter endpoint, headers and TLS, the sampler, the batching and the resource attributes
+// are read by the OpenTelemetry SDK from the standard OTEL_* environment variables.
+// It returns the function flushing the pending spans and stopping the provider.
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch Protocol() {
+	case ProtocolGRPC:
+		exporter, err = otlptracegrpc.New(ctx)
+	case ProtocolHTTPProtobuf:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, use %s or %s", Protocol(), ProtocolGRPC, ProtocolHTTPProtobuf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("OpenTelemetry error", "err", err)
+	}))
+
+	slog.Info("Tracing enabled", "protocol", Protocol(), "endpoint", endpoint())
+	return provider.Shutdown, nil
+}
+
+// endpoint returns the configured OTLP traces endpoint for logging.
+func endpoint() string {
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value != "" {
+			return value
+		}
+	}
+	return "default"
+}
+
+// EndSpan records err as the status of the span, if it is not nil, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
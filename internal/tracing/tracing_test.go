@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnabled(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		env     map[string]string
+		enabled bool
+	}{
+		{name: "no variables", env: map[string]string{}, enabled: false},
+		{name: "endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://otel:4318"}, enabled: true},
+		{name: "traces endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://otel:4318/v1/traces"}, enabled: true},
+		{name: "exporter", env: map[string]string{"OTEL_TRACES_EXPORTER": "otlp"}, enabled: true},
+		{name: "exporter none", env: map[string]string{
+			"OTEL_TRACES_EXPORTER":        "none",
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://otel:4318",
+		}, enabled: false},
+		{name: "sdk disabled", env: map[string]string{
+			"OTEL_SDK_DISABLED":    "true",
+			"OTEL_TRACES_EXPORTER": "otlp",
+		}, enabled: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{
+				"OTEL_SDK_DISABLED",
+				"OTEL_TRACES_EXPORTER",
+				"OTEL_EXPORTER_OTLP_ENDPOINT",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+			} {
+				t.Setenv(name, test.env[name])
+			}
+			require.Equal(t, test.enabled, Enabled())
+		})
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+	require.Equal(t, ProtocolHTTPProtobuf, Protocol())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", ProtocolGRPC)
+	require.Equal(t, ProtocolGRPC, Protocol())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", ProtocolHTTPProtobuf)
+	require.Equal(t, ProtocolHTTPProtobuf, Protocol(), "The traces protocol should take precedence")
+}
+
+func TestSetupUnsupportedProtocol(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/json")
+
+	_, err := Setup(context.Background(), "test")
+	require.ErrorContains(t, err, "unsupported OTLP protocol")
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+
+	shutdown, err := Setup(context.Background(), "test")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}